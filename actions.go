@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+type containerAction int
+
+const (
+	StopAction containerAction = iota
+	StartAction
+	RestartAction
+	KillAction
+	PauseAction
+	UnpauseAction
+)
+
+// seconds to wait before docker kills a container on stop/restart
+const stopTimeout = 10
+
+var actionNames = map[containerAction]string{
+	StopAction:    "Stop",
+	StartAction:   "Start",
+	RestartAction: "Restart",
+	KillAction:    "Kill",
+	PauseAction:   "Pause",
+	UnpauseAction: "Unpause",
+}
+
+var actionPastTense = map[containerAction]string{
+	StopAction:    "Stopped",
+	StartAction:   "Started",
+	RestartAction: "Restarted",
+	KillAction:    "Killed",
+	PauseAction:   "Paused",
+	UnpauseAction: "Unpaused",
+}
+
+// destructive actions need to be confirmed before being sent to docker
+func (a containerAction) destructive() bool {
+	switch a {
+	case StopAction, RestartAction, KillAction:
+		return true
+	}
+	return false
+}
+
+// appliesTo tells if the action makes sense for the container's current state, when acting on a whole project
+func (a containerAction) appliesTo(cont container) bool {
+	switch a {
+	case StopAction, KillAction:
+		return cont.running()
+	case StartAction:
+		return !cont.running()
+	case PauseAction:
+		return cont.running() && !cont.State.Paused
+	case UnpauseAction:
+		return cont.State.Paused
+	}
+	return true
+}
+
+// pendingAction is an action on one container, or on the services of a compose project
+type pendingAction struct {
+	action containerAction
+	name   string
+	conts  containerSlice
+}
+
+func actionOn(action containerAction, cont container) pendingAction {
+	return pendingAction{action, cont.shortName(), containerSlice{cont}}
+}
+
+// projectAction acts on the project's containers that the action applies to
+func projectAction(action containerAction, project *composeProject) pendingAction {
+	p := pendingAction{action: action, name: "project " + project.title()}
+	for _, cont := range project.containers {
+		if action.appliesTo(cont) {
+			p.conts = append(p.conts, cont)
+		}
+	}
+	return p
+}
+
+func (p pendingAction) prompt() string {
+	if len(p.conts) > 1 {
+		return fmt.Sprintf("%s %s (%d containers)? (y/n)", actionNames[p.action], p.name, len(p.conts))
+	}
+	return fmt.Sprintf("%s %s? (y/n)", actionNames[p.action], p.name)
+}
+
+func (sl *StatsListener) PerformAction(action containerAction, id string) error {
+	switch action {
+	case StopAction:
+		return sl.DockerClient.StopContainer(id, stopTimeout)
+	case StartAction:
+		return sl.DockerClient.StartContainer(id, nil)
+	case RestartAction:
+		return sl.DockerClient.RestartContainer(id, stopTimeout)
+	case KillAction:
+		return sl.DockerClient.KillContainer(goDocker.KillContainerOptions{ID: id})
+	case PauseAction:
+		return sl.DockerClient.PauseContainer(id)
+	case UnpauseAction:
+		return sl.DockerClient.UnpauseContainer(id)
+	}
+	return fmt.Errorf("unknown action %d", action)
+}
+
+// runAction performs the action in the background and reports the outcome on events
+func runAction(ls listenerSet, p pendingAction, events chan<- event) {
+	go func() {
+		var (
+			errs   []string
+			result string
+		)
+		for _, cont := range p.conts {
+			if err := ls.forHost(cont.host).PerformAction(p.action, cont.ID); err != nil {
+				errs = append(errs, strings.TrimSpace(err.Error()))
+			}
+		}
+		switch {
+		case len(errs) == 0:
+			result = actionPastTense[p.action] + " " + p.name
+		case len(p.conts) == 1:
+			result = fmt.Sprintf("%s %s failed: %s", actionNames[p.action], p.name, errs[0])
+		default:
+			result = fmt.Sprintf("%s %s failed for %d of %d containers: %s", actionNames[p.action], p.name, len(errs), len(p.conts), errs[0])
+		}
+		events <- actionFinished{result}
+	}()
+}
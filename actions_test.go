@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunActionReachesTheDaemon(t *testing.T) {
+	var (
+		alpha     = newFakeDocker()
+		beta      = newFakeDocker()
+		listeners = listenerSet{
+			&StatsListener{DockerClient: alpha, Host: "alpha"},
+			&StatsListener{DockerClient: beta, Host: "beta"},
+		}
+		events = make(chan event)
+		web    = testContainer("w", "web", "nginx", true, time.Hour, nil)
+		db     = testContainer("d", "db", "postgres", true, time.Hour, nil)
+	)
+	web.host, db.host = "alpha", "beta"
+	alpha.create(web.ID, "web", "nginx")
+	beta.create(db.ID, "db", "postgres")
+
+	for _, tc := range []struct {
+		action containerAction
+		result string
+	}{
+		{StopAction, "Stopped web"},
+		{KillAction, "Killed web"},
+		{PauseAction, "Paused web"},
+	} {
+		runAction(listeners, actionOn(tc.action, web), events)
+		if e := receive(t, events, "the action to finish").(actionFinished); e.Result != tc.result {
+			t.Errorf("expected %q, got %q", tc.result, e.Result)
+		}
+	}
+	if want := []string{"stop " + web.ID, "kill " + web.ID, "pause " + web.ID}; !reflect.DeepEqual(alpha.actions, want) {
+		t.Errorf("expected web's host to be asked to %v, got %v", want, alpha.actions)
+	}
+
+	// each container goes to its own host, and one failing doesn't stop the others
+	gone := testContainer("g", "gone", "busybox", true, time.Hour, nil)
+	gone.host = "beta"
+	runAction(listeners, pendingAction{StopAction, "project shop", containerSlice{web, db, gone}}, events)
+	if e := receive(t, events, "the action to finish").(actionFinished); !strings.HasPrefix(e.Result, "Stop project shop failed for 1 of 3 containers: ") {
+		t.Errorf("expected the missing container to be reported, got %q", e.Result)
+	}
+	if want := []string{"stop " + db.ID}; !reflect.DeepEqual(beta.actions, want) {
+		t.Errorf("expected db's host to be asked to %v, got %v", want, beta.actions)
+	}
+}
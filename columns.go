@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	units "github.com/docker/go-units"
+)
+
+// column is a field of the container table. Columns with a flex weight share the width left over by the fixed ones.
+type column struct {
+	name   string
+	header string
+	width  int
+	flex   int
+	value  func(cont container, stats ContainerStats) string
+}
+
+var tableColumns = []column{
+	{"name", "Name", 16, 3, func(cont container, stats ContainerStats) string {
+		return cont.shortName()
+	}},
+	{"id", "ID", 12, 0, func(cont container, stats ContainerStats) string {
+		return cont.ID[:12]
+	}},
+	{"host", "Host", 12, 0, func(cont container, stats ContainerStats) string {
+		return cont.host
+	}},
+	{"image", "Image", 12, 2, func(cont container, stats ContainerStats) string {
+		return cont.imageName()
+	}},
+	{"status", "Status", 24, 0, func(cont container, stats ContainerStats) string {
+		return cont.status()
+	}},
+	{"health", "Health", 9, 0, func(cont container, stats ContainerStats) string {
+		return cont.health()
+	}},
+	{"cpu", "%CPU", 6, 0, func(cont container, stats ContainerStats) string {
+		if !cont.running() {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", stats.CPUPercent)
+	}},
+	{"mem", "%MEM", 6, 0, func(cont container, stats ContainerStats) string {
+		if !cont.running() {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", stats.MemPercent)
+	}},
+	{"ports", "Ports", 12, 2, func(cont container, stats ContainerStats) string {
+		return strings.Join(cont.ports(), ",")
+	}},
+	{"uptime", "Uptime", 14, 0, func(cont container, stats ContainerStats) string {
+		if !cont.running() {
+			return ""
+		}
+		return units.HumanDuration(time.Since(cont.State.StartedAt))
+	}},
+	{"ip", "IP", 15, 0, func(cont container, stats ContainerStats) string {
+		return strings.Join(cont.ipAddresses(), ",")
+	}},
+	{"labels", "Labels", 12, 2, func(cont container, stats ContainerStats) string {
+		return strings.Join(cont.labelPairs(), ",")
+	}},
+	{"net", "Net rx/tx", 20, 0, func(cont container, stats ContainerStats) string {
+		if !cont.running() {
+			return ""
+		}
+		return rateString(stats.NetRxRate) + " " + rateString(stats.NetTxRate)
+	}},
+	{"io", "IO r/w", 20, 0, func(cont container, stats ContainerStats) string {
+		if !cont.running() {
+			return ""
+		}
+		return rateString(stats.BlockReadRate) + " " + rateString(stats.BlockWriteRate)
+	}},
+}
+
+const defaultColumns = "name,image,status,cpu,mem,ports"
+
+// parseColumns reads a comma separated list of column names
+func parseColumns(spec string) (cols []column, err error) {
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		col, ok := findColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q, expected one of %s", name, strings.Join(columnNames(), ", "))
+		}
+		cols = append(cols, col)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+	return
+}
+
+func findColumn(name string) (column, bool) {
+	for _, col := range tableColumns {
+		if col.name == name {
+			return col, true
+		}
+	}
+	return column{}, false
+}
+
+func columnNames() []string {
+	names := make([]string, len(tableColumns))
+	for i, col := range tableColumns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// columnWidths gives the fixed columns their width and shares out what is left between the flexible ones.
+// Columns are separated by one character, the last one's separator falls on the border.
+func columnWidths(cols []column, totalWidth int) []int {
+	var (
+		widths    = make([]int, len(cols))
+		remaining = totalWidth - len(cols) + 1
+		totalFlex = 0
+	)
+	for i, col := range cols {
+		widths[i] = col.width
+		remaining -= col.width
+		totalFlex += col.flex
+	}
+	if remaining <= 0 || totalFlex == 0 {
+		return widths
+	}
+	shared := remaining
+	for i, col := range cols {
+		extra := shared * col.flex / totalFlex
+		widths[i] += extra
+		remaining -= extra
+	}
+	// hand out the rounding leftovers to the first flexible column
+	for i, col := range cols {
+		if col.flex > 0 {
+			widths[i] += remaining
+			break
+		}
+	}
+	return widths
+}
+
+func (cont container) ipAddresses() (ips []string) {
+	if cont.NetworkSettings == nil {
+		return
+	}
+	for _, name := range sortedKeys(cont.NetworkSettings.Networks) {
+		if ip := cont.NetworkSettings.Networks[name].IPAddress; len(ip) > 0 {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 && len(cont.NetworkSettings.IPAddress) > 0 {
+		ips = append(ips, cont.NetworkSettings.IPAddress)
+	}
+	return
+}
+
+func (cont container) labelPairs() (pairs []string) {
+	if cont.Config == nil {
+		return
+	}
+	for key, value := range cont.Config.Labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return
+}
+
+// tableRows lays the rows out as table cells, starting at the offset. The first column is prefixed with the
+// chart number like the name list. Project headers only fill in the name, status and usage columns.
+// Running containers past their thresholds are returned with their alert level.
+func (rows listRows) tableRows(offset int, cols []column, stats map[string]ContainerStats) (cells [][]string, stopped []int, headers []int, alerts map[int]alertLevel) {
+	alerts = make(map[int]alertLevel)
+	var (
+		numbers = rows.numbers()
+		grouped = len(rows) > 0 && rows[0].header()
+	)
+	for index, row := range rows {
+		if index < offset {
+			continue
+		}
+		cellRow := make([]string, len(cols))
+		if row.header() {
+			cpu, mem := row.project.totals(stats)
+			for i, col := range cols {
+				switch col.name {
+				case "status":
+					cellRow[i] = fmt.Sprintf("%d/%d up", row.project.containers.numRunning(), len(row.project.containers))
+				case "cpu":
+					cellRow[i] = fmt.Sprintf("%.1f", cpu)
+				case "mem":
+					cellRow[i] = fmt.Sprintf("%.1f", mem)
+				}
+			}
+			cellRow[0] = row.project.marker() + " " + row.project.title()
+			headers = append(headers, len(cells))
+		} else {
+			for i, col := range cols {
+				cellRow[i] = col.value(row.cont, stats[row.cont.ID])
+			}
+			if grouped && cols[0].name == "name" {
+				cellRow[0] = row.cont.serviceName()
+			}
+			cellRow[0] = numbers[index] + ". " + cellRow[0]
+			if grouped {
+				cellRow[0] = "  " + cellRow[0]
+			}
+			if !row.cont.running() {
+				stopped = append(stopped, len(cells))
+			} else if level := row.cont.alertLevel(stats[row.cont.ID]); level != NormalLevel {
+				alerts[len(cells)] = level
+			}
+		}
+		cells = append(cells, cellRow)
+	}
+	return
+}
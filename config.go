@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config holds the defaults read from the config file, flags given on the command line take precedence
+type config struct {
+	Endpoints  []endpointConfig  `yaml:"endpoints"`
+	Refresh    time.Duration     `yaml:"refresh"`
+	Sort       string            `yaml:"sort"`
+	Info       string            `yaml:"info"`
+	Columns    []string          `yaml:"columns"`
+	Theme      string            `yaml:"theme"`
+	Colors     colorTheme        `yaml:"colors"`
+	Thresholds thresholds        `yaml:"thresholds"`
+	Keys       map[string]string `yaml:"keys"`
+}
+
+// endpointConfig is an endpoint in the config file, given either as a [name=]endpoint string or as a mapping with
+// its own tls settings. Endpoints without tls settings of their own use the --tls flags.
+type endpointConfig struct {
+	Name      string `yaml:"name"`
+	Endpoint  string `yaml:"endpoint"`
+	TLSCert   string `yaml:"tls-cert"`
+	TLSKey    string `yaml:"tls-key"`
+	TLSCA     string `yaml:"tls-ca"`
+	TLSVerify bool   `yaml:"tls-verify"`
+}
+
+func (ec *endpointConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&ec.Endpoint)
+	}
+	// the file's decoder checks for unknown fields, but not in a node decoded here
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch key := node.Content[i].Value; key {
+		case "name", "endpoint", "tls-cert", "tls-key", "tls-ca", "tls-verify":
+		default:
+			return fmt.Errorf("line %d: unknown endpoint field %q", node.Content[i].Line, key)
+		}
+	}
+	type plain endpointConfig
+	return node.Decode((*plain)(ec))
+}
+
+func (ec endpointConfig) tls() tlsOptions {
+	return tlsOptions{TLSCert: ec.TLSCert, TLSKey: ec.TLSKey, TLSCA: ec.TLSCA, TLSVerify: ec.TLSVerify}
+}
+
+// defaultConfigPath is $XDG_CONFIG_HOME/dockdash/config.yaml, falling back to ~/.config
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "dockdash", "config.yaml")
+}
+
+// loadConfig reads the config file at path. A missing file is only an error if it was asked for by name.
+func loadConfig(path string, required bool) (cfg config, err error) {
+	// thresholds left out of the file keep their defaults
+	cfg.Thresholds = alertThresholds
+	if len(path) == 0 {
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err = decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// keyActions name the ui events that can be bound to other keys
+var keyActions = map[string]uiEvent{
+	"quit":     KeyQ,
+	"inspect":  KeyI,
+	"stop":     KeyS,
+	"start":    KeyT,
+	"restart":  KeyR,
+	"kill":     KeyK,
+	"pause":    KeyP,
+	"unpause":  KeyU,
+	"all":      KeyA,
+	"images":   KeyTab,
+	"logs":     KeyL,
+	"history":  KeyW,
+	"sort":     KeyO,
+	"reverse":  KeyShiftO,
+	"search":   KeySlash,
+	"exec":     KeyE,
+	"group":    KeyG,
+	"collapse": KeyC,
+	"table":    KeyV,
+	"events":   KeyShiftE,
+	"rewind":   KeyLeftBracket,
+	"forward":  KeyRightBracket,
+	"slower":   KeyMinus,
+	"faster":   KeyPlus,
+}
+
+// confirmKeys answer the prompt asking to confirm an action, so they can't be bound to one
+var confirmKeys = map[string]bool{"y": true, "n": true, "<Escape>": true}
+
+// remapKeys binds actions to new keys, given as termui key ids like "x", "X" or "<C-x>". The action's default
+// key is unbound. Binding a key that is still bound to something else, or one that answers a confirmation prompt,
+// is an error, and keys are left as they were.
+func remapKeys(keys map[string]uiEvent, remap map[string]string) error {
+	var (
+		remapped = make(map[string]uiEvent, len(keys))
+		boundTo  = make(map[string]string)
+	)
+	for id, event := range keys {
+		remapped[id] = event
+	}
+	for _, action := range sortedKeys(remap) {
+		event, ok := keyActions[action]
+		if !ok {
+			return fmt.Errorf("unknown action %q in keys, expected one of %s", action, strings.Join(sortedKeys(keyActions), ", "))
+		}
+		for id, bound := range remapped {
+			if bound == event {
+				delete(remapped, id)
+			}
+		}
+	}
+	// checked once every remapped action's default key is free, so two actions can swap keys
+	for _, action := range sortedKeys(remap) {
+		id := remap[action]
+		switch {
+		case confirmKeys[id]:
+			return fmt.Errorf("%s can't be bound to %s, it answers confirmation prompts", id, action)
+		case len(boundTo[id]) > 0:
+			return fmt.Errorf("%s is bound to both %s and %s", id, boundTo[id], action)
+		}
+		if bound, ok := remapped[id]; ok {
+			if name := keyActionName(bound); len(name) > 0 {
+				return fmt.Errorf("%s can't be bound to %s, it is already bound to %s", id, action, name)
+			}
+			return fmt.Errorf("%s can't be bound to %s, the dashboard already uses it", id, action)
+		}
+		boundTo[id] = action
+	}
+	for action, id := range remap {
+		remapped[id] = keyActions[action]
+	}
+
+	for id := range keys {
+		delete(keys, id)
+	}
+	for id, event := range remapped {
+		keys[id] = event
+	}
+	return nil
+}
+
+// keyActionName names the action a ui event can be bound as, if any
+func keyActionName(event uiEvent) string {
+	for _, action := range sortedKeys(keyActions) {
+		if keyActions[action] == event {
+			return action
+		}
+	}
+	return ""
+}
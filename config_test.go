@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemapKeys(t *testing.T) {
+	keys := map[string]uiEvent{"s": KeyS, "t": KeyT, "k": KeyK, "y": KeyY, "n": KeyN, "<Escape>": KeyN, "<Down>": KeyArrowDown}
+	if err := remapKeys(keys, map[string]string{"stop": "t", "start": "s", "kill": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if keys["t"] != KeyS || keys["s"] != KeyT || keys["x"] != KeyK {
+		t.Errorf("expected stop and start swapped and kill on x, got %v", keys)
+	}
+	if _, ok := keys["k"]; ok {
+		t.Error("expected kill's default key to be unbound")
+	}
+
+	for _, tc := range []struct {
+		remap map[string]string
+		err   string
+	}{
+		{map[string]string{"kill": "t"}, "already bound to stop"},
+		{map[string]string{"kill": "<Down>"}, "already uses it"},
+		{map[string]string{"kill": "z", "pause": "z"}, "bound to both kill and pause"},
+		{map[string]string{"kill": "y"}, "answers confirmation prompts"},
+		{map[string]string{"quit": "<Escape>"}, "answers confirmation prompts"},
+		{map[string]string{"explode": "z"}, "unknown action"},
+	} {
+		before := len(keys)
+		err := remapKeys(keys, tc.remap)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("remapping %v, expected an error containing %q, got %v", tc.remap, tc.err, err)
+		}
+		if len(keys) != before || keys["x"] != KeyK {
+			t.Errorf("remapping %v, expected the keys to be left as they were, got %v", tc.remap, keys)
+		}
+	}
+}
@@ -1,106 +1,314 @@
 package main
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	. "github.com/byrnedo/dockdash/logger"
+	units "github.com/docker/go-units"
 	goDocker "github.com/fsouza/go-dockerclient"
 )
 
 type container struct {
 	*goDocker.Container
+	// the docker host the container runs on, empty when there is only one host
+	host string
+}
+
+func (cont container) shortName() string {
+	return strings.TrimLeft(cont.Name, "/")
+}
+
+// imageName is the image the container was created from, as it was given
+func (cont container) imageName() string {
+	if cont.Config == nil {
+		return ""
+	}
+	return cont.Config.Image
+}
+
+func (cont container) env() []string {
+	if cont.Config == nil {
+		return nil
+	}
+	return cont.Config.Env
+}
+
+func (cont container) entrypoint() []string {
+	if cont.Config == nil {
+		return nil
+	}
+	return cont.Config.Entrypoint
+}
+
+func (cont container) binds() []string {
+	if cont.HostConfig == nil {
+		return nil
+	}
+	return cont.HostConfig.Binds
+}
+
+// ports lists the published ports, as createPortsSlice formats them
+func (cont container) ports() []string {
+	if cont.NetworkSettings == nil {
+		return nil
+	}
+	return createPortsSlice(cont.NetworkSettings.Ports)
+}
+
+func (cont container) running() bool {
+	return cont.State.Running
+}
+
+// status gives a docker ps style summary of the container state
+func (cont container) status() string {
+	switch {
+	case cont.State.Restarting:
+		return fmt.Sprintf("Restarting (%d) %s ago", cont.State.ExitCode, units.HumanDuration(time.Since(cont.State.FinishedAt)))
+	case cont.State.Running:
+		status := "Up " + units.HumanDuration(time.Since(cont.State.StartedAt))
+		if notes := cont.runningNotes(); len(notes) > 0 {
+			status += " " + notes
+		}
+		return status
+	case cont.State.FinishedAt.IsZero():
+		return "Created"
+	default:
+		status := fmt.Sprintf("Exited (%d) %s ago", cont.State.ExitCode, units.HumanDuration(time.Since(cont.State.FinishedAt)))
+		if cont.State.OOMKilled {
+			status += " (OOM killed)"
+		}
+		return status
+	}
+}
+
+// runningNotes point out the states of a running container worth knowing about, shown after its uptime and name.
+// A restarting container has its status shown instead.
+func (cont container) runningNotes() string {
+	var notes []string
+	if cont.State.Paused {
+		notes = append(notes, "(Paused)")
+	}
+	if health := cont.healthSummary(); len(health) > 0 {
+		notes = append(notes, health)
+	}
+	return strings.Join(notes, " ")
+}
+
+type sortKey int
+
+const (
+	SortUptime sortKey = iota
+	SortCPU
+	SortMem
+	SortName
+	SortImage
+)
+
+var sortKeyNames = map[sortKey]string{
+	SortUptime: "uptime",
+	SortCPU:    "cpu",
+	SortMem:    "mem",
+	SortName:   "name",
+	SortImage:  "image",
+}
+
+func parseSortKey(name string) (sortKey, error) {
+	for key, keyName := range sortKeyNames {
+		if keyName == name {
+			return key, nil
+		}
+	}
+	return SortUptime, fmt.Errorf("unknown sort key %q, expected uptime, cpu, mem, name or image", name)
+}
+
+type sortOrder struct {
+	key     sortKey
+	reverse bool
+}
+
+func (o sortOrder) String() string {
+	if o.reverse {
+		return "↑" + sortKeyNames[o.key]
+	}
+	return "↓" + sortKeyNames[o.key]
+}
+
+// next cycles through the sort keys
+func (o sortOrder) next() sortOrder {
+	o.key = (o.key + 1) % sortKey(len(sortKeyNames))
+	return o
 }
 
 type containerSlice []container
 
-func (cs containerSlice) sort() {
+// sort puts running containers first, followed by stopped ones, so running containers line up with the stats charts.
+// Busiest, newest and alphabetically first come first unless reversed. Stopped containers have no stats so
+// are sorted most recently stopped first when sorting by cpu or memory.
+func (cs containerSlice) sort(order sortOrder, stats map[string]ContainerStats) {
+	// compare is below 0 when a goes before b by the sort key, and 0 when the key ties
+	compare := func(a container, b container) int {
+		switch order.key {
+		case SortCPU:
+			if a.running() {
+				return compareFloats(stats[b.ID].CPUPercent, stats[a.ID].CPUPercent)
+			}
+		case SortMem:
+			if a.running() {
+				return compareFloats(stats[b.ID].MemPercent, stats[a.ID].MemPercent)
+			}
+		case SortName:
+			return strings.Compare(a.shortName(), b.shortName())
+		case SortImage:
+			return strings.Compare(a.imageName(), b.imageName())
+		}
+		if !a.running() {
+			return compareTimes(b.State.FinishedAt, a.State.FinishedAt)
+		}
+		return compareTimes(b.State.StartedAt, a.State.StartedAt)
+	}
+
 	sort.Slice(cs, func(i int, j int) bool {
-		return cs[i].State.StartedAt.After(cs[j].State.StartedAt)
+		a, b := cs[i], cs[j]
+		if a.running() != b.running() {
+			return a.running()
+		}
+		if order.reverse {
+			a, b = b, a
+		}
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		// the containers come out of a map, ties are broken the same way every time so rows don't swap on a refresh
+		if a.shortName() != b.shortName() {
+			return a.shortName() < b.shortName()
+		}
+		return a.ID < b.ID
 	})
 }
 
-type containerMap map[string]container
-
-func (cm containerMap) toSlice() containerSlice {
-	s := containerSlice(toSlice(cm))
-	s.sort()
-	return s
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
 
-func toSlice[U comparable, V any](m map[U]V) (sl []V) {
-	sl = make([]V, len(m))
-	var i = 0
-	for _, val := range m {
-		sl[i] = val
-		i++
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
 	}
-	return
+	return 0
 }
 
-func (cm containerMap) namesAndInfo(offset int, infoType dockerInfoType, inspectMode bool) ([]string, []string) {
-	var numContainers = len(cm)
-	if offset > numContainers {
-		offset = numContainers - 1
+// runningStats lines up the stats with the running containers, for the charts
+func (cs containerSlice) runningStats(stats map[string]ContainerStats) (running []ContainerStats) {
+	for _, cont := range cs {
+		if !cont.running() {
+			continue
+		}
+		contStats, ok := stats[cont.ID]
+		if !ok {
+			contStats = ContainerStats{ID: cont.ID, Name: cont.shortName()}
+		}
+		running = append(running, contStats)
 	}
+	return
+}
 
-	var (
-		info                []string
-		numContainersSubset = numContainers - offset
-		names               = make([]string, numContainersSubset)
-		containersSorted    = cm.toSlice()
-		nameStr             = ""
-		containerNumber     = 0
-	)
+type containerMap map[string]container
 
-	if !inspectMode {
-		info = make([]string, numContainersSubset)
+// visible returns the containers to display, leaving out stopped ones unless showAll is set
+func (cm containerMap) visible(showAll bool) containerMap {
+	if showAll {
+		return cm
 	}
+	running := make(containerMap, len(cm))
+	for id, cont := range cm {
+		if cont.running() {
+			running[id] = cont
+		}
+	}
+	return running
+}
 
-	for index, cont := range containersSorted {
-		if index < offset {
-			continue
+func (cm containerMap) numRunning() (num int) {
+	return containerSlice(toSlice(cm)).numRunning()
+}
+
+func (cs containerSlice) numRunning() (num int) {
+	for _, cont := range cs {
+		if cont.running() {
+			num++
 		}
+	}
+	return
+}
 
-		containerNumber = numContainers - index
-		nameStr = strconv.Itoa(containerNumber) + ". " + cont.ID[:12] + " " + strings.TrimLeft(cont.Name, "/")
+func (cm containerMap) sorted(order sortOrder, stats map[string]ContainerStats) containerSlice {
+	s := containerSlice(toSlice(cm))
+	s.sort(order, stats)
+	return s
+}
 
-		if inspectMode && index == offset {
-			names[index-offset] = "*" + nameStr
-			info = cont.inspectInfo(index, offset, infoType)
-		} else {
-			names[index-offset] = " " + nameStr
-			if !inspectMode {
-				info[index-offset] = cont.regularInfo(index, offset, infoType)
-			}
+// hostWidth is the width of the host column, zero when there is only one host
+func (cs containerSlice) hostWidth() (width int) {
+	for _, cont := range cs {
+		if len(cont.host) > width {
+			width = len(cont.host)
 		}
+	}
+	return
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
 
+func toSlice[U comparable, V any](m map[U]V) (sl []V) {
+	sl = make([]V, len(m))
+	var i = 0
+	for _, val := range m {
+		sl[i] = val
+		i++
 	}
-	return names, info
+	return
 }
 
-func (cont container) regularInfo(index int, offset int, infoType dockerInfoType) (info string) {
+func (cont container) regularInfo(index int, offset int, infoType dockerInfoType, stats ContainerStats) (info string) {
 
 	switch infoType {
 	case ImageInfo:
-		info = cont.Config.Image
+		info = cont.imageName()
 	case Names:
 		info = cont.Name
 		if cont.Node != nil {
 			info = cont.Node.Name + info
 		}
 	case PortInfo:
-		info = strings.Join(createPortsSlice(cont.NetworkSettings.Ports), ",")
+		info = strings.Join(cont.ports(), ",")
 	case BindInfo:
-		info = strings.TrimRight(strings.Join(cont.HostConfig.Binds, ","), ",")
+		info = strings.TrimRight(strings.Join(cont.binds(), ","), ",")
 	case CommandInfo:
 		info = cont.Path + " " + strings.Join(cont.Args, " ")
 	case EnvInfo:
-		info = strings.TrimRight(strings.Join(cont.Config.Env, ","), ",")
+		info = strings.TrimRight(strings.Join(cont.env(), ","), ",")
 	case EntrypointInfo:
-		info = strings.Join(cont.Config.Entrypoint, " ")
+		info = strings.Join(cont.entrypoint(), " ")
 	case VolumesInfo:
 		volStr := ""
 		for intVol, hostVol := range cont.Volumes {
@@ -109,16 +317,26 @@ func (cont container) regularInfo(index int, offset int, infoType dockerInfoType
 		info = strings.TrimRight(volStr, ",")
 	case TimeInfo:
 		info = cont.State.StartedAt.Format(time.RubyDate)
+	case StatusInfo:
+		info = cont.status()
+	case NetIOInfo:
+		if cont.running() {
+			info = "rx " + rateString(stats.NetRxRate) + "  tx " + rateString(stats.NetTxRate)
+		}
+	case BlockIOInfo:
+		if cont.running() {
+			info = "read " + rateString(stats.BlockReadRate) + "  write " + rateString(stats.BlockWriteRate)
+		}
 	default:
 		Error.Println("Unhandled info type", infoType)
 	}
 	return
 }
 
-func (cont container) inspectInfo(index int, offset int, infoType dockerInfoType) (info []string) {
+func (cont container) inspectInfo(index int, offset int, infoType dockerInfoType, stats ContainerStats) (info []string) {
 	switch infoType {
 	case ImageInfo:
-		info = []string{cont.Config.Image}
+		info = []string{cont.imageName()}
 	case Names:
 		if cont.Node != nil {
 			info = []string{cont.Node.Name, cont.Name}
@@ -126,10 +344,10 @@ func (cont container) inspectInfo(index int, offset int, infoType dockerInfoType
 			info = []string{cont.Name}
 		}
 	case PortInfo:
-		info = createPortsSlice(cont.NetworkSettings.Ports)
+		info = cont.ports()
 	case BindInfo:
-		info = make([]string, len(cont.HostConfig.Binds))
-		for i, binding := range cont.HostConfig.Binds {
+		info = make([]string, len(cont.binds()))
+		for i, binding := range cont.binds() {
 			info[i] = binding
 		}
 	case CommandInfo:
@@ -138,13 +356,13 @@ func (cont container) inspectInfo(index int, offset int, infoType dockerInfoType
 			info[i] = arg
 		}
 	case EnvInfo:
-		info = make([]string, len(cont.Config.Env))
-		for i, env := range cont.Config.Env {
+		info = make([]string, len(cont.env()))
+		for i, env := range cont.env() {
 			info[i] = env
 		}
 	case EntrypointInfo:
-		info = make([]string, len(cont.Config.Entrypoint))
-		for i, entrypoint := range cont.Config.Entrypoint {
+		info = make([]string, len(cont.entrypoint()))
+		for i, entrypoint := range cont.entrypoint() {
 			info[i] = entrypoint
 		}
 	case VolumesInfo:
@@ -156,6 +374,31 @@ func (cont container) inspectInfo(index int, offset int, infoType dockerInfoType
 		}
 	case TimeInfo:
 		info = []string{cont.State.StartedAt.Format(time.RubyDate)}
+	case StatusInfo:
+		info = []string{
+			cont.status(),
+			"Started: " + cont.State.StartedAt.Format(time.RubyDate),
+		}
+		info = append(info, cont.healthInfo()...)
+		if !cont.running() && !cont.State.FinishedAt.IsZero() {
+			info = append(info,
+				"Finished: "+cont.State.FinishedAt.Format(time.RubyDate),
+				"Exit code: "+strconv.Itoa(cont.State.ExitCode),
+			)
+		}
+		if len(cont.State.Error) > 0 {
+			info = append(info, "Error: "+cont.State.Error)
+		}
+	case NetIOInfo:
+		info = []string{
+			"Received: " + rateString(stats.NetRxRate) + ", " + units.HumanSize(float64(stats.NetRx)) + " total",
+			"Sent: " + rateString(stats.NetTxRate) + ", " + units.HumanSize(float64(stats.NetTx)) + " total",
+		}
+	case BlockIOInfo:
+		info = []string{
+			"Read: " + rateString(stats.BlockReadRate) + ", " + units.HumanSize(float64(stats.BlockRead)) + " total",
+			"Written: " + rateString(stats.BlockWriteRate) + ", " + units.HumanSize(float64(stats.BlockWrite)) + " total",
+		}
 	default:
 		Error.Println("Unhandled info type", infoType)
 	}
@@ -177,3 +420,15 @@ func createPortsSlice(ports map[goDocker.Port][]goDocker.PortBinding) (portsSlic
 	}
 	return
 }
+
+func rateString(bytesPerSecond float64) string {
+	return units.HumanSize(bytesPerSecond) + "/s"
+}
+
+// greyedOut wraps text in termui style markup for stopped containers
+func greyedOut(text string) string {
+	if len(text) == 0 {
+		return text
+	}
+	return "[" + text + "](fg:stopped)"
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortBreaksTiesByNameThenID(t *testing.T) {
+	started := time.Now().Add(-time.Hour)
+	containers := containerMap{}
+	for _, id := range []string{"f", "e", "d", "c", "b", "a"} {
+		name := "replica"
+		if id == "a" {
+			name = "zeta"
+		}
+		cont := testContainer(id, name, "busybox", true, time.Hour, nil)
+		// started at the same moment, so the uptime ties too
+		cont.State.StartedAt = started
+		containers[cont.ID] = cont
+	}
+	// idle, with the same image
+	stats := map[string]ContainerStats{}
+
+	want := []string{fakeID("b"), fakeID("c"), fakeID("d"), fakeID("e"), fakeID("f"), fakeID("a")}
+	for _, key := range []sortKey{SortCPU, SortMem, SortImage, SortUptime} {
+		for i := 0; i < 20; i++ {
+			sorted := containers.sorted(sortOrder{key: key}, stats)
+			for j, cont := range sorted {
+				if cont.ID != want[j] {
+					t.Fatalf("sorting by %v, row %d is %s, want %s", key, j, cont.ID[:1], want[j][:1])
+				}
+			}
+		}
+	}
+}
+
+func TestStatusMarksRestartingOnce(t *testing.T) {
+	cont := testContainer("a", "web", "nginx", true, time.Hour, nil)
+	cont.State.Restarting = true
+	cont.State.ExitCode = 1
+	cont.State.FinishedAt = time.Now().Add(-time.Minute)
+	if status := cont.status(); !strings.HasPrefix(status, "Restarting (1) ") || strings.Count(status, "Restarting") != 1 {
+		t.Errorf("expected a single restarting marker, got %q", status)
+	}
+
+	cont.State.Restarting = false
+	cont.State.Paused = true
+	if status := cont.status(); !strings.HasPrefix(status, "Up ") || !strings.HasSuffix(status, " (Paused)") {
+		t.Errorf("expected a paused container to be up and marked, got %q", status)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+// dockerAPI is the part of the docker client dockdash uses, so the listener can be run against a fake daemon
+type dockerAPI interface {
+	Ping() error
+	AddEventListener(listener chan<- *goDocker.APIEvents) error
+	RemoveEventListener(listener chan *goDocker.APIEvents) error
+	ListContainers(opts goDocker.ListContainersOptions) ([]goDocker.APIContainers, error)
+	InspectContainer(id string) (*goDocker.Container, error)
+	ListImages(opts goDocker.ListImagesOptions) ([]goDocker.APIImages, error)
+	// Stats streams stats on opts.Stats until opts.Done is closed or the container stops, closing opts.Stats
+	// when it returns
+	Stats(opts goDocker.StatsOptions) error
+	Logs(opts goDocker.LogsOptions) error
+
+	StartContainer(id string, hostConfig *goDocker.HostConfig) error
+	StopContainer(id string, timeout uint) error
+	RestartContainer(id string, timeout uint) error
+	KillContainer(opts goDocker.KillContainerOptions) error
+	PauseContainer(id string) error
+	UnpauseContainer(id string) error
+
+	CreateExec(opts goDocker.CreateExecOptions) (*goDocker.Exec, error)
+	StartExecNonBlocking(id string, opts goDocker.StartExecOptions) (goDocker.CloseWaiter, error)
+	ResizeExecTTY(id string, height int, width int) error
+	InspectExec(id string) (*goDocker.ExecInspect, error)
+}
+
+var _ dockerAPI = (*goDocker.Client)(nil)
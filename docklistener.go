@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
-	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/byrnedo/dockdash/logger"
 	goDocker "github.com/fsouza/go-dockerclient"
@@ -18,183 +21,562 @@ type StatsResult struct {
 	Stats     goDocker.Stats
 }
 
-type StatsResultSlice []*StatsResult
-
-func (p StatsResultSlice) Len() int {
-	return len(p)
-}
-
-func (p StatsResultSlice) Less(i, j int) bool {
-	return p[i].Container.State.StartedAt.After(p[j].Container.State.StartedAt)
-}
-
-func (p StatsResultSlice) Swap(i, j int) {
-	p[i], p[j] = p[j], p[i]
-}
-
 type ChartData struct {
 	DataLabels []string
 	Data       []float64
+	// how far each bar is past the thresholds, to color it by
+	Levels []alertLevel
 }
 
 func (cd ChartData) Offset(offset int) ChartData {
+	if offset > len(cd.Data) {
+		offset = len(cd.Data)
+	}
 	cd.Data = cd.Data[offset:]
 	cd.DataLabels = cd.DataLabels[offset:]
+	cd.Levels = cd.Levels[offset:]
 	return cd
 }
 
 func (cd ChartData) UpdateBarChart(uiChart *widgets.BarChart) {
 
 	uiChart.Data = cd.Data
+	// termui scales the bars to the highest value, which has to be above 0 for the bars to have a height
+	uiChart.MaxVal = 1
+	for _, value := range cd.Data {
+		if value > uiChart.MaxVal {
+			uiChart.MaxVal = value
+		}
+	}
 	numBars := len(cd.Data)
 	uiChart.BarColors = make([]ui.Color, numBars)
 	uiChart.LabelStyles = make([]ui.Style, numBars)
 	uiChart.NumStyles = make([]ui.Style, numBars)
 	uiChart.Labels = make([]string, numBars)
 	for i := 0; i < numBars; i++ {
-		uiChart.BarColors[i] = ui.ColorWhite
-		uiChart.LabelStyles[i] = ui.Style{Fg: ui.ColorWhite, Bg: ui.ColorClear}
+		style := alertStyles[cd.Levels[i]]
+		uiChart.BarColors[i] = style.Fg
+		if style.Fg == ui.ColorClear {
+			uiChart.BarColors[i] = chartColor
+		}
+		uiChart.LabelStyles[i] = style
 		uiChart.NumStyles[i] = ui.Style{Fg: ui.ColorBlack}
 		uiChart.Labels[i] = fmt.Sprintf("%3s", cd.DataLabels[i])
 	}
 }
 
+// ContainerStats holds the figures derived from the latest stats sample of a running container
+type ContainerStats struct {
+	Host       string  `json:"host,omitempty"`
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Image      string  `json:"image"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemPercent float64 `json:"mem_percent"`
+	MemUsage   uint64  `json:"mem_usage"`
+	MemLimit   uint64  `json:"mem_limit"`
+	NetRx      uint64  `json:"net_rx_bytes"`
+	NetTx      uint64  `json:"net_tx_bytes"`
+	BlockRead  uint64  `json:"block_read_bytes"`
+	BlockWrite uint64  `json:"block_write_bytes"`
+	ioRates
+	// number of samples received since the stream started
+	Samples int `json:"-"`
+}
+
+// ioRates are in bytes per second, worked out from two consecutive samples
+type ioRates struct {
+	NetRxRate      float64 `json:"net_rx_rate"`
+	NetTxRate      float64 `json:"net_tx_rate"`
+	BlockReadRate  float64 `json:"block_read_rate"`
+	BlockWriteRate float64 `json:"block_write_rate"`
+}
+
+func newIORates(previous *goDocker.Stats, current *goDocker.Stats) (rates ioRates) {
+	if previous.Read.IsZero() || !current.Read.After(previous.Read) {
+		return
+	}
+	var (
+		seconds             = current.Read.Sub(previous.Read).Seconds()
+		prevRx, prevTx      = networkTotals(previous)
+		rx, tx              = networkTotals(current)
+		prevRead, prevWrite = blockIOTotals(previous)
+		read, write         = blockIOTotals(current)
+	)
+	rates.NetRxRate = perSecond(prevRx, rx, seconds)
+	rates.NetTxRate = perSecond(prevTx, tx, seconds)
+	rates.BlockReadRate = perSecond(prevRead, read, seconds)
+	rates.BlockWriteRate = perSecond(prevWrite, write, seconds)
+	return
+}
+
+func perSecond(previous uint64, current uint64, seconds float64) float64 {
+	// counters go back to zero when a container restarts
+	if current < previous {
+		return 0
+	}
+	return float64(current-previous) / seconds
+}
+
 type StatsMsg struct {
-	CpuChart ChartData
-	MemChart ChartData
+	Host       string
+	Containers []ContainerStats
+}
+
+// mergeStats combines the latest stats of every host into one message
+func mergeStats(byHost map[string]StatsMsg) (merged StatsMsg) {
+	for _, host := range sortedKeys(byHost) {
+		merged.Containers = append(merged.Containers, byHost[host].Containers...)
+	}
+	return
+}
+
+// byID indexes the container stats, it is safe to call on a nil message
+func (sm *StatsMsg) byID() map[string]ContainerStats {
+	stats := make(map[string]ContainerStats)
+	if sm == nil {
+		return stats
+	}
+	for _, cs := range sm.Containers {
+		stats[cs.ID] = cs
+	}
+	return stats
+}
+
+// totals sums the io rates of every container
+func (sm *StatsMsg) totals() (total ioRates) {
+	if sm == nil {
+		return
+	}
+	for _, cs := range sm.Containers {
+		total.NetRxRate += cs.NetRxRate
+		total.NetTxRate += cs.NetTxRate
+		total.BlockReadRate += cs.BlockReadRate
+		total.BlockWriteRate += cs.BlockWriteRate
+	}
+	return
+}
+
+// retry delays after losing the connection to the daemon
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+var errEventsClosed = errors.New("event stream closed")
+
+// connStatus reports a host losing, or with no error regaining, its connection to the daemon
+type connStatus struct {
+	Host  string
+	Err   error
+	Retry time.Duration
+}
+
+func (cs connStatus) String() string {
+	msg := cs.Err.Error()
+	if cs.Retry > 0 {
+		msg = fmt.Sprintf("disconnected, retrying in %s: %s", cs.Retry, msg)
+	}
+	if len(cs.Host) > 0 {
+		msg = cs.Host + ": " + msg
+	}
+	return msg
 }
 
 type StatsListener struct {
-	DockerClient         *goDocker.Client
+	DockerClient dockerAPI
+	// name shown next to the host's containers, empty when there is only one host
+	Host                 string
 	ctx                  context.Context
 	cncl                 context.CancelFunc
+	eventsMutex          sync.Mutex
 	dockerEventChan      chan *goDocker.APIEvents
 	statsResultsChan     chan StatsResult
 	statsResultsDoneChan chan string
+	logsMutex            sync.Mutex
+	logStreams           map[string]*logStream
+	ready                chan struct{}
+	history              *statsHistory
+	// opened again when the listener reconnects, if ssh has exited
+	tunnel *sshTunnel
+	// the error connecting before the listener was opened, returned by Open. The listener retries if it has a
+	// client.
+	connectErr error
+	// stopped containers are only listed, and inspected, once they are to be shown, as a host can have a lot of them
+	stoppedMutex  sync.Mutex
+	listStopped   bool
+	stoppedWanted chan struct{}
 }
 
-func (sl *StatsListener) Open(newContChan chan<- goDocker.Container, removeContChan chan<- string, drawStatsChan chan<- StatsMsg) {
-	sl.ctx, sl.cncl = context.WithCancel(context.Background())
-
-	sl.dockerEventChan = make(chan *goDocker.APIEvents, 10)
+// Open starts listening to the daemon, sending what happens on events. The existing containers are sent in the
+// background, Ready is closed once they have been. If the daemon can't be reached the error is returned, and sent
+// as the host's status while it is retried in the background, until the listener is closed.
+func (sl *StatsListener) Open(events chan<- event) error {
 	sl.statsResultsChan = make(chan StatsResult)
 	sl.statsResultsDoneChan = make(chan string)
+	sl.ready = make(chan struct{})
+	sl.history = newStatsHistory()
+	sl.ctx, sl.cncl = context.WithCancel(context.Background())
+	sl.stoppedMutex.Lock()
+	sl.stoppedWanted = make(chan struct{}, 1)
+	sl.stoppedMutex.Unlock()
 
-	err := sl.DockerClient.AddEventListener(sl.dockerEventChan)
-	if err != nil {
-		panic("Failed to add event listener: " + err.Error())
+	var (
+		containers []goDocker.APIContainers
+		err        = sl.connectErr
+	)
+	switch {
+	case err != nil && sl.DockerClient == nil:
+		// there is no client to retry with
+		go sl.sendStatus(events, connStatus{Host: sl.Host, Err: err})
+		return err
+	case err == nil:
+		containers, err = sl.connect()
 	}
 
-	go sl.statsRenderingRoutine(drawStatsChan)
+	go sl.statsRenderingRoutine(events)
 
-	go sl.dockerEventRoutingRoutine(newContChan, removeContChan)
+	go sl.dockerEventRoutingRoutine(containers, err, events)
 
-	containers, _ := sl.DockerClient.ListContainers(goDocker.ListContainersOptions{})
-	Info.Println("Listing initial", len(containers), "containers as started")
-	for _, cont := range containers {
-		Info.Println("Marking", cont.ID, "as started")
-		sl.dockerEventChan <- &goDocker.APIEvents{ID: cont.ID, Status: "start"}
+	if err != nil {
+		return err
 	}
+	sl.sendImages(events)
 
 	Info.Println("stats listener open")
+	return nil
+}
+
+// connect registers a new event listener with the daemon, and lists the containers, stopped ones included once
+// ListStopped has been called. An ssh tunnel is opened again first if ssh has exited.
+func (sl *StatsListener) connect() ([]goDocker.APIContainers, error) {
+	if sl.tunnel != nil {
+		if err := sl.tunnel.ensureOpen(); err != nil {
+			return nil, err
+		}
+	}
+	if err := sl.DockerClient.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to connect to docker: %w", err)
+	}
+
+	events := make(chan *goDocker.APIEvents, 10)
+	if err := sl.DockerClient.AddEventListener(events); err != nil {
+		return nil, fmt.Errorf("failed to add event listener: %w", err)
+	}
+
+	containers, err := sl.DockerClient.ListContainers(goDocker.ListContainersOptions{All: sl.stoppedListed()})
+	if err != nil {
+		sl.DockerClient.RemoveEventListener(events)
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	sl.eventsMutex.Lock()
+	sl.dockerEventChan = events
+	sl.eventsMutex.Unlock()
+	return containers, nil
+}
+
+// ListStopped has the stopped containers listed and sent on too, from now on
+func (sl *StatsListener) ListStopped() {
+	sl.stoppedMutex.Lock()
+	defer sl.stoppedMutex.Unlock()
+	if sl.listStopped {
+		return
+	}
+	sl.listStopped = true
+	if sl.stoppedWanted != nil {
+		// the open listener lists them from its own routine
+		sl.stoppedWanted <- struct{}{}
+	}
+}
+
+func (sl *StatsListener) stoppedListed() bool {
+	sl.stoppedMutex.Lock()
+	defer sl.stoppedMutex.Unlock()
+	return sl.listStopped
+}
+
+// sendStatus reports the host's connection status, unless the listener is closed first
+func (sl *StatsListener) sendStatus(events chan<- event, status connStatus) bool {
+	select {
+	case <-sl.ctx.Done():
+		return false
+	case events <- connChanged{status}:
+		return true
+	}
+}
+
+// reconnect retries connecting after err, backing off up to reconnectMaxDelay, until it succeeds or the listener
+// is closed
+func (sl *StatsListener) reconnect(events chan<- event, err error) ([]goDocker.APIContainers, bool) {
+	delay := reconnectMinDelay
+	for {
+		Error.Println("Disconnected from docker, retrying in", delay, ":", err)
+		if !sl.sendStatus(events, connStatus{sl.Host, err, delay}) {
+			return nil, false
+		}
+		select {
+		case <-sl.ctx.Done():
+			return nil, false
+		case <-time.After(delay):
+		}
+
+		var containers []goDocker.APIContainers
+		if containers, err = sl.connect(); err == nil {
+			Info.Println("Reconnected to docker")
+			if !sl.sendStatus(events, connStatus{Host: sl.Host}) {
+				return nil, false
+			}
+			return containers, true
+		}
+
+		if delay *= 2; delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+func (sl *StatsListener) sendImages(events chan<- event) {
+	images, err := sl.DockerClient.ListImages(goDocker.ListImagesOptions{})
+	if err != nil {
+		Error.Println("Failed to list images:", err)
+		return
+	}
+	events <- imagesUpdated{imagesMsg{sl.Host, newImageSlice(sl.Host, images)}}
+}
+
+// History returns the samples recorded for the container within the window, oldest first
+func (sl *StatsListener) History(id string, window time.Duration) []statsSample {
+	if sl.history == nil {
+		return nil
+	}
+	return sl.history.Since(id, time.Now().Add(-window))
+}
+
+// Ready is closed once the containers that existed when the listener was opened have been sent on
+func (sl *StatsListener) Ready() <-chan struct{} {
+	return sl.ready
 }
 
 func (sl *StatsListener) Close() {
+	if sl.tunnel != nil {
+		defer sl.tunnel.Close()
+	}
 	if sl.cncl == nil {
 		return
 	}
-	if err := sl.DockerClient.RemoveEventListener(sl.dockerEventChan); err != nil {
-		panic(err)
+	sl.eventsMutex.Lock()
+	events := sl.dockerEventChan
+	sl.eventsMutex.Unlock()
+	// nil if the daemon was never reached
+	if events != nil {
+		if err := sl.DockerClient.RemoveEventListener(events); err != nil {
+			Error.Println("Failed to remove event listener:", err)
+		}
 	}
 	sl.cncl()
-	close(sl.dockerEventChan)
-	close(sl.statsResultsChan)
-	close(sl.statsResultsDoneChan)
 }
 
-func (sl *StatsListener) dockerEventRoutingRoutine(newContainerChan chan<- goDocker.Container, removeContainerChan chan<- string) {
+// dockerEventRoutingRoutine follows the daemon's events, starting with the listed containers. listErr is the error
+// connecting when the listener was opened, in which case it reconnects first.
+func (sl *StatsListener) dockerEventRoutingRoutine(listed []goDocker.APIContainers, listErr error, events chan<- event) {
 	var (
-		statsDoneChannels                 = make(map[string]chan bool)
-		startsResultInterceptChannels     = make(map[string]chan *goDocker.Stats)
-		startsResultInterceptDoneChannels = make(map[string]chan bool)
+		statsDoneChannels = make(map[string]chan bool)
+		known             = make(map[string]bool)
 	)
 
-	closeAndDeleteChannels := func(id string) {
-		close(statsDoneChannels[id])
-		delete(statsDoneChannels, id)
-		delete(startsResultInterceptChannels, id)
-		delete(startsResultInterceptDoneChannels, id)
-		sl.statsResultsDoneChan <- id
+	stopStats := func(id string) {
+		if done, ok := statsDoneChannels[id]; ok {
+			Info.Println("Stopping stats routine for", id)
+			close(done)
+			delete(statsDoneChannels, id)
+		}
 	}
 
+	// reinspect sends on the container's current state, after events that change it without starting or stopping it
+	reinspect := func(id string) {
+		if !known[id] {
+			return
+		}
+		cont, err := sl.DockerClient.InspectContainer(id)
+		if err != nil {
+			Error.Println("Failed to inspect container", id, ":", err)
+			return
+		}
+		events <- containerUpdated{Container: container{cont, sl.Host}}
+	}
+
+	handleEvent := func(e *goDocker.APIEvents) {
+		if e.Type == "image" {
+			switch e.Status {
+			case "pull", "tag", "untag", "delete":
+				Info.Println("image", e.ID, e.Status)
+				sl.sendImages(events)
+			}
+			return
+		}
+		switch e.Status {
+		case "start":
+			Info.Println(e.ID, "started")
+			cont, err := sl.DockerClient.InspectContainer(e.ID)
+			if err != nil {
+				Error.Println("Failed to inspect new container", e.ID, ":", err)
+				return
+			}
+			known[cont.ID] = true
+			events <- containerUpdated{Container: container{cont, sl.Host}}
+			if _, ok := statsDoneChannels[cont.ID]; !ok && cont.State.Running {
+				statsDoneChannels[cont.ID] = sl.startStats(cont)
+			}
+		case "create":
+			Info.Println(e.ID, "created")
+			cont, err := sl.DockerClient.InspectContainer(e.ID)
+			if err != nil {
+				Error.Println("Failed to inspect new container", e.ID, ":", err)
+				return
+			}
+			known[cont.ID] = true
+			events <- containerUpdated{Container: container{cont, sl.Host}}
+		case "die":
+			Info.Println(e.ID, "died")
+			stopStats(e.ID)
+			// log streams are left to drain, docker closes them once the last lines are sent
+			// re-inspect to pick up the exit code, it may already be gone if it was run with --rm
+			cont, err := sl.DockerClient.InspectContainer(e.ID)
+			if err != nil {
+				delete(known, e.ID)
+				events <- containerRemoved{e.ID}
+				return
+			}
+			events <- containerUpdated{Container: container{cont, sl.Host}}
+		case "destroy":
+			Info.Println(e.ID, "destroyed")
+			stopStats(e.ID)
+			sl.StopLogs(e.ID)
+			sl.history.Remove(e.ID)
+			delete(known, e.ID)
+			events <- containerRemoved{e.ID}
+		case "pause", "unpause", "rename", "update", "kill", "oom", "restart":
+			Info.Println(e.ID, e.Status)
+			reinspect(e.ID)
+		default:
+			if strings.HasPrefix(e.Status, healthEventPrefix) {
+				Info.Println(e.ID, e.Status)
+				// picks up the health check's output too
+				reinspect(e.ID)
+			}
+		}
+	}
+
+	// syncContainers sends on every listed container, and removes the ones that have gone since the last listing
+	syncContainers := func(containers []goDocker.APIContainers) {
+		Info.Println("Listing", len(containers), "containers")
+		listedIDs := make(map[string]bool, len(containers))
+		for _, cont := range containers {
+			listedIDs[cont.ID] = true
+			if cont.State == "running" || cont.State == "paused" {
+				handleEvent(&goDocker.APIEvents{ID: cont.ID, Status: "start"})
+			} else {
+				handleEvent(&goDocker.APIEvents{ID: cont.ID, Status: "create"})
+			}
+		}
+		for id := range known {
+			if !listedIDs[id] {
+				handleEvent(&goDocker.APIEvents{ID: id, Status: "destroy"})
+			}
+		}
+	}
+
+	// addStopped sends on the stopped containers that aren't known yet, once they are wanted
+	addStopped := func() {
+		containers, err := sl.DockerClient.ListContainers(goDocker.ListContainersOptions{All: true})
+		if err != nil {
+			Error.Println("Failed to list stopped containers:", err)
+			return
+		}
+		for _, cont := range containers {
+			if !known[cont.ID] && cont.State != "running" && cont.State != "paused" {
+				handleEvent(&goDocker.APIEvents{ID: cont.ID, Status: "create"})
+			}
+		}
+	}
+
+	if listErr != nil {
+		var ok bool
+		if listed, ok = sl.reconnect(events, listErr); !ok {
+			return
+		}
+		sl.sendImages(events)
+	}
+	syncContainers(listed)
+	close(sl.ready)
+
 	for {
 		select {
 		case <-sl.ctx.Done():
 			return
-		case e := <-sl.dockerEventChan:
-			if e == nil {
+		case <-sl.stoppedWanted:
+			addStopped()
+		case e, ok := <-sl.dockerEventChan:
+			if ok {
+				if e != nil {
+					events <- eventLogged{newEventLogEntry(sl.Host, e)}
+					handleEvent(e)
+				}
 				continue
 			}
-			switch e.Status {
-			case "start":
-				Info.Println(e.ID, "started")
-				cont, err := sl.DockerClient.InspectContainer(e.ID)
-				if err != nil {
-					Error.Println("Failed to inspect new container", e.ID, ":", err)
-					continue
-				}
-				newContainerChan <- *cont
-				sl.statsResultsChan <- StatsResult{*cont, goDocker.Stats{}}
-
-				statsDoneChannels[cont.ID] = make(chan bool, 1)
-				startsResultInterceptChannels[cont.ID] = make(chan *goDocker.Stats)
-				startsResultInterceptDoneChannels[cont.ID] = make(chan bool)
-
-				spinOffStatsInterceptor := func() {
-					for {
-						select {
-						case <-sl.ctx.Done():
-							return
-						case stat := <-startsResultInterceptChannels[cont.ID]:
-							if cont != nil {
-								if stat == nil {
-									stat = &goDocker.Stats{}
-								}
-								sl.statsResultsChan <- StatsResult{*cont, *stat}
-							}
-						case _ = <-startsResultInterceptDoneChannels[cont.ID]:
-							return
-						}
-					}
-				}
-				go spinOffStatsInterceptor()
-
-				Info.Println("Starting stats routine for", cont.ID)
-				spinOffStatsListener := func() {
-					if err := sl.DockerClient.Stats(goDocker.StatsOptions{ID: cont.ID, Stats: startsResultInterceptChannels[cont.ID], Stream: true, Done: statsDoneChannels[cont.ID]}); err != nil {
-						Error.Println("Error starting statistics handler for id", cont.ID, ":", err.Error())
-						startsResultInterceptDoneChannels[cont.ID] <- true
-					}
-					closeAndDeleteChannels(cont.ID)
-				}
-				go spinOffStatsListener()
-			case "die":
-				removeContainerChan <- e.ID
-				sl.statsResultsDoneChan <- e.ID
-
-				Info.Println("Stopping stats routine for", e.ID)
-				statsDoneChannels[e.ID] <- true
-				startsResultInterceptDoneChannels[e.ID] <- true
-				closeAndDeleteChannels(e.ID)
+			// the client closes its listeners when the event stream ends, which happens when the daemon restarts
+			for id := range statsDoneChannels {
+				stopStats(id)
 			}
+			containers, ok := sl.reconnect(events, errEventsClosed)
+			if !ok {
+				return
+			}
+			syncContainers(containers)
+			sl.sendImages(events)
 		}
 	}
 }
 
-func (sl *StatsListener) statsRenderingRoutine(drawStatsChan chan<- StatsMsg) {
+// startStats streams stats for the container until the returned channel is closed
+func (sl *StatsListener) startStats(cont *goDocker.Container) chan bool {
+	var (
+		done      = make(chan bool)
+		statsChan = make(chan *goDocker.Stats)
+	)
+
+	sl.statsResultsChan <- StatsResult{*cont, goDocker.Stats{}}
+
+	go func() {
+		// the stats channel is closed by the client once the stream ends
+		for stat := range statsChan {
+			select {
+			case <-sl.ctx.Done():
+			case sl.statsResultsChan <- StatsResult{*cont, *stat}:
+			}
+		}
+		select {
+		case <-sl.ctx.Done():
+		case sl.statsResultsDoneChan <- cont.ID:
+		}
+	}()
+
+	Info.Println("Starting stats routine for", cont.ID)
+	go func() {
+		if err := sl.DockerClient.Stats(goDocker.StatsOptions{ID: cont.ID, Stats: statsChan, Stream: true, Done: done, Context: sl.ctx}); err != nil {
+			Error.Println("Error starting statistics handler for id", cont.ID, ":", err.Error())
+		}
+	}()
+	return done
+}
+
+func (sl *StatsListener) statsRenderingRoutine(events chan<- event) {
 
 	var (
 		statsList = make(map[string]*StatsResult)
+		samples   = make(map[string]int)
+		rates     = make(map[string]ioRates)
 	)
 
 	for {
@@ -202,50 +584,117 @@ func (sl *StatsListener) statsRenderingRoutine(drawStatsChan chan<- StatsMsg) {
 		case <-sl.ctx.Done():
 			return
 		case msg := <-sl.statsResultsChan:
+			if previous, ok := statsList[msg.Container.ID]; ok {
+				rates[msg.Container.ID] = newIORates(&previous.Stats, &msg.Stats)
+			}
 			statsList[msg.Container.ID] = &msg
-			statsCpuChart, statsMemChart := updateStatsBarCharts(statsList)
-			drawStatsChan <- StatsMsg{*statsCpuChart, *statsMemChart}
+			if msg.Stats.Read.IsZero() {
+				samples[msg.Container.ID] = 0
+			} else {
+				samples[msg.Container.ID]++
+			}
+			if !msg.Stats.PreRead.IsZero() {
+				cs := newContainerStats(&msg)
+				sl.history.Add(msg.Container.ID, statsSample{msg.Stats.Read, cs.CPUPercent, cs.MemPercent})
+			}
+			sl.publishStats(events, newStatsMsg(statsList, samples, rates))
 		case id := <-sl.statsResultsDoneChan:
 			delete(statsList, id)
-			statsCpuChart, statsMemChart := updateStatsBarCharts(statsList)
-			drawStatsChan <- StatsMsg{*statsCpuChart, *statsMemChart}
+			delete(samples, id)
+			delete(rates, id)
+			sl.publishStats(events, newStatsMsg(statsList, samples, rates))
+		}
+	}
+}
+
+func (sl *StatsListener) publishStats(events chan<- event, msg StatsMsg) {
+	msg.Host = sl.Host
+	for i := range msg.Containers {
+		msg.Containers[i].Host = sl.Host
+	}
+	events <- statsUpdated{msg}
+}
+
+func newStatsMsg(statsList map[string]*StatsResult, samples map[string]int, rates map[string]ioRates) StatsMsg {
+	containers := make([]ContainerStats, 0, len(statsList))
+	for id, stats := range statsList {
+		cs := newContainerStats(stats)
+		cs.Samples = samples[id]
+		cs.ioRates = rates[id]
+		containers = append(containers, cs)
+	}
+
+	return StatsMsg{Containers: containers}
+}
+
+func newContainerStats(stats *StatsResult) ContainerStats {
+	cs := ContainerStats{
+		ID:         stats.Container.ID,
+		Name:       strings.TrimLeft(stats.Container.Name, "/"),
+		CPUPercent: math.Round(calculateCPUPercent(&stats.Stats)*10) / 10,
+		MemUsage:   stats.Stats.MemoryStats.Usage,
+		MemLimit:   stats.Stats.MemoryStats.Limit,
+	}
+	if stats.Container.Config != nil {
+		cs.Image = stats.Container.Config.Image
+	}
+	if cs.MemLimit != 0 {
+		cs.MemPercent = math.Round((float64(cs.MemUsage)/float64(cs.MemLimit)*100)*10) / 10
+	}
+	cs.NetRx, cs.NetTx = networkTotals(&stats.Stats)
+	cs.BlockRead, cs.BlockWrite = blockIOTotals(&stats.Stats)
+	return cs
+}
+
+func networkTotals(v *goDocker.Stats) (rx uint64, tx uint64) {
+	if len(v.Networks) == 0 {
+		// api versions before 1.21 only report a single interface
+		return v.Network.RxBytes, v.Network.TxBytes
+	}
+	for _, network := range v.Networks {
+		rx += network.RxBytes
+		tx += network.TxBytes
+	}
+	return
+}
+
+func blockIOTotals(v *goDocker.Stats) (read uint64, write uint64) {
+	for _, entry := range v.BlkioStats.IOServiceBytesRecursive {
+		// cgroup v1 capitalises the op, v2 doesn't
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			read += entry.Value
+		case "write":
+			write += entry.Value
 		}
 	}
+	return
 }
 
-func updateStatsBarCharts(statsList map[string]*StatsResult) (statsCpuChart *ChartData, statsMemChart *ChartData) {
+func updateStatsBarCharts(containers []ContainerStats) (statsCpuChart *ChartData, statsMemChart *ChartData) {
 	statsCpuChart = &ChartData{}
 	statsMemChart = &ChartData{}
 
 	var (
-		statsListLen = len(statsList)
-		orderedList  = make(StatsResultSlice, statsListLen)
+		statsListLen = len(containers)
 	)
 
 	statsCpuChart.DataLabels = make([]string, statsListLen)
 	statsCpuChart.Data = make([]float64, statsListLen)
+	statsCpuChart.Levels = make([]alertLevel, statsListLen)
 
 	statsMemChart.DataLabels = make([]string, statsListLen)
 	statsMemChart.Data = make([]float64, statsListLen)
+	statsMemChart.Levels = make([]alertLevel, statsListLen)
 
-	count := 0
-	for _, nums := range statsList {
-		orderedList[count] = nums
-		count++
-	}
-
-	sort.Sort(orderedList)
-
-	for count, stats := range orderedList {
+	for count, stats := range containers {
 		statsCpuChart.DataLabels[count] = strconv.Itoa(statsListLen - count)
-		statsCpuChart.Data[count] = math.Round(calculateCPUPercent(&stats.Stats)*10) / 10
+		statsCpuChart.Data[count] = stats.CPUPercent
+		statsCpuChart.Levels[count] = alertThresholds.CPU.level(stats.CPUPercent)
 
 		statsMemChart.DataLabels[count] = strconv.Itoa(statsListLen - count)
-		if stats.Stats.MemoryStats.Limit != 0 {
-			statsMemChart.Data[count] = math.Round((float64(stats.Stats.MemoryStats.Usage)/float64(stats.Stats.MemoryStats.Limit)*100)*10) / 10
-		} else {
-			statsMemChart.Data[count] = 0
-		}
+		statsMemChart.Data[count] = stats.MemPercent
+		statsMemChart.Levels[count] = alertThresholds.Mem.level(stats.MemPercent)
 	}
 	return statsCpuChart, statsMemChart
 }
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+const receiveTimeout = 5 * time.Second
+
+func receive[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(receiveTimeout):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+	panic("unreachable")
+}
+
+// openedListener is a listener opened on a fake daemon. Its events are sorted onto buffered channels, one per kind, so
+// it never blocks on the test.
+type openedListener struct {
+	*StatsListener
+	docker   *fakeDocker
+	newConts chan container
+	removed  chan string
+	stats    chan StatsMsg
+	images   chan imagesMsg
+	status   chan connStatus
+	eventLog chan eventLogEntry
+}
+
+func openListener(t *testing.T, docker *fakeDocker) *openedListener {
+	t.Helper()
+	l, err := startListener(t, docker)
+	if err != nil {
+		t.Fatal("open:", err)
+	}
+	receive(t, l.Ready(), "the listener to be ready")
+	return l
+}
+
+// startListener opens a listener without waiting for it to be ready, returning Open's error
+func startListener(t *testing.T, docker *fakeDocker) (*openedListener, error) {
+	t.Helper()
+	return startStatsListener(t, docker, &StatsListener{DockerClient: docker, Host: "test"})
+}
+
+func startStatsListener(t *testing.T, docker *fakeDocker, sl *StatsListener) (*openedListener, error) {
+	t.Helper()
+	var (
+		events = make(chan event)
+		done   = make(chan struct{})
+		l      = &openedListener{
+			StatsListener: sl,
+			docker:        docker,
+			newConts:      make(chan container, 100),
+			removed:       make(chan string, 100),
+			stats:         make(chan StatsMsg, 100),
+			images:        make(chan imagesMsg, 100),
+			status:        make(chan connStatus, 100),
+			eventLog:      make(chan eventLogEntry, 100),
+		}
+	)
+	go l.route(events, done)
+	t.Cleanup(func() {
+		l.Close()
+		close(done)
+	})
+	return l, l.Open(events)
+}
+
+func (l *openedListener) route(events <-chan event, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case e := <-events:
+			switch e := e.(type) {
+			case containerUpdated:
+				l.newConts <- e.Container
+			case containerRemoved:
+				l.removed <- e.ID
+			case statsUpdated:
+				l.stats <- e.Stats
+			case imagesUpdated:
+				l.images <- e.Images
+			case connChanged:
+				l.status <- e.Status
+			case eventLogged:
+				l.eventLog <- e.Entry
+			}
+		}
+	}
+}
+
+// statsFor waits for a stats message with a sample for the container
+func (l *openedListener) statsFor(t *testing.T, id string) ContainerStats {
+	t.Helper()
+	deadline := time.After(receiveTimeout)
+	for {
+		select {
+		case msg := <-l.stats:
+			for _, cs := range msg.Containers {
+				if cs.ID == id && cs.CPUPercent+cs.MemPercent > 0 {
+					return cs
+				}
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for stats of %s", id)
+		}
+	}
+}
+
+func TestOpenSendsListedContainers(t *testing.T) {
+	docker := newFakeDocker()
+	docker.create(fakeID("a"), "web", "nginx")
+	docker.start(fakeID("a"))
+	docker.create(fakeID("b"), "db", "postgres")
+	docker.images = []goDocker.APIImages{{ID: "sha256:1", RepoTags: []string{"nginx:latest"}}}
+
+	l := openListener(t, docker)
+
+	if web := receive(t, l.newConts, "the running container"); web.ID != fakeID("a") || !web.running() || web.host != "test" {
+		t.Errorf("web should be running on host test, got %s %+v", web.shortName(), web.State)
+	}
+	if id := receive(t, docker.streamOpened, "a stats stream"); id != fakeID("a") {
+		t.Errorf("stats streamed for %s, only web is running", id)
+	}
+	if images := receive(t, l.images, "images"); len(images.Images) != 1 || images.Host != "test" {
+		t.Errorf("expected the one image of host test, got %+v", images)
+	}
+
+	// stopped containers are only listed once they are shown
+	select {
+	case cont := <-l.newConts:
+		t.Fatalf("expected only the running container, got %s", cont.shortName())
+	default:
+	}
+	l.ListStopped()
+	if db := receive(t, l.newConts, "the stopped container"); db.ID != fakeID("b") || db.running() {
+		t.Errorf("expected the stopped db, got %s running=%v", db.shortName(), db.running())
+	}
+	l.ListStopped()
+	select {
+	case cont := <-l.newConts:
+		t.Errorf("expected the stopped containers to be listed once, got %s again", cont.shortName())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestContainerLifecycleEvents(t *testing.T) {
+	docker := newFakeDocker()
+	l := openListener(t, docker)
+	id := fakeID("c")
+
+	docker.create(id, "worker", "busybox")
+	if cont := receive(t, l.newConts, "the created container"); cont.running() || cont.shortName() != "worker" {
+		t.Errorf("expected a stopped worker, got %s running=%v", cont.shortName(), cont.running())
+	}
+
+	docker.start(id)
+	if cont := receive(t, l.newConts, "the started container"); !cont.running() {
+		t.Error("expected the container to be running once started")
+	}
+	receive(t, docker.streamOpened, "a stats stream")
+
+	docker.die(id, 3)
+	cont := receive(t, l.newConts, "the dead container")
+	if cont.running() || cont.State.ExitCode != 3 {
+		t.Errorf("expected the container to have exited with 3, got running=%v code=%d", cont.running(), cont.State.ExitCode)
+	}
+	if status := cont.status(); status[:10] != "Exited (3)" {
+		t.Errorf("unexpected status %q", status)
+	}
+
+	docker.destroy(id)
+	if removed := receive(t, l.removed, "the removed container"); removed != id {
+		t.Errorf("expected %s to be removed, got %s", id, removed)
+	}
+}
+
+func TestRenameIsReinspected(t *testing.T) {
+	docker := newFakeDocker()
+	docker.create(fakeID("d"), "old", "busybox")
+	docker.start(fakeID("d"))
+	l := openListener(t, docker)
+	receive(t, l.newConts, "the listed container")
+
+	docker.rename(fakeID("d"), "new")
+	if cont := receive(t, l.newConts, "the renamed container"); cont.shortName() != "new" {
+		t.Errorf("expected the new name, got %s", cont.shortName())
+	}
+}
+
+func TestEventsAreLogged(t *testing.T) {
+	docker := newFakeDocker()
+	l := openListener(t, docker)
+
+	docker.create(fakeID("e"), "logged", "busybox")
+	entry := receive(t, l.eventLog, "an event log entry")
+	if entry.Type != "container" || entry.Action != "create" || entry.Name != "logged" || entry.Host != "test" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestStatsArePublished(t *testing.T) {
+	docker := newFakeDocker()
+	id := fakeID("f")
+	docker.create(id, "busy", "busybox")
+	docker.start(id)
+	l := openListener(t, docker)
+	receive(t, docker.streamOpened, "a stats stream")
+
+	stats := &goDocker.Stats{Read: time.Now(), PreRead: time.Now().Add(-time.Second)}
+	stats.CPUStats.CPUUsage.TotalUsage = 200
+	stats.CPUStats.SystemCPUUsage = 1000
+	stats.CPUStats.OnlineCPUs = 2
+	stats.PreCPUStats.CPUUsage.TotalUsage = 100
+	stats.MemoryStats.Usage = 50
+	stats.MemoryStats.Limit = 200
+	if !docker.sendStats(id, stats) {
+		t.Fatal("no stats stream open")
+	}
+
+	cs := l.statsFor(t, id)
+	if cs.CPUPercent != 20 || cs.MemPercent != 25 || cs.Host != "test" {
+		t.Errorf("expected 20%% cpu and 25%% memory on host test, got %+v", cs)
+	}
+	if samples := l.History(id, time.Minute); len(samples) != 1 || samples[0].CPU != 20 {
+		t.Errorf("expected the sample in the history, got %+v", samples)
+	}
+}
+
+func TestReconnectAfterEventsClose(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect delay")
+	}
+	docker := newFakeDocker()
+	docker.create(fakeID("g"), "survivor", "busybox")
+	docker.start(fakeID("g"))
+	l := openListener(t, docker)
+	receive(t, l.newConts, "the listed container")
+
+	docker.closeEvents()
+	if status := receive(t, l.status, "the disconnected status"); status.Err != errEventsClosed {
+		t.Errorf("expected the events closed error, got %v", status.Err)
+	}
+	if status := receive(t, l.status, "the reconnected status"); status.Err != nil {
+		t.Errorf("expected to reconnect, got %v", status.Err)
+	}
+	if cont := receive(t, l.newConts, "the relisted container"); cont.ID != fakeID("g") {
+		t.Errorf("expected the container to be listed again, got %s", cont.ID)
+	}
+
+	// events are followed again on the new listener
+	docker.create(fakeID("h"), "newcomer", "busybox")
+	if cont := receive(t, l.newConts, "the container created after reconnecting"); cont.ID != fakeID("h") {
+		t.Errorf("expected the new container, got %s", cont.ID)
+	}
+}
+
+func TestOpenRetriesAnUnreachableDaemon(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect delay")
+	}
+	docker := newFakeDocker()
+	docker.pingErr = errors.New("daemon restarting")
+	docker.create(fakeID("i"), "early", "busybox")
+	docker.start(fakeID("i"))
+
+	l, err := startListener(t, docker)
+	if err == nil {
+		t.Fatal("expected Open to return the connection error")
+	}
+	if status := receive(t, l.status, "the disconnected status"); status.Host != "test" || status.Err == nil || status.Retry == 0 {
+		t.Errorf("expected host test to be retried, got %+v", status)
+	}
+
+	docker.mu.Lock()
+	docker.pingErr = nil
+	docker.mu.Unlock()
+	if status := receive(t, l.status, "the reconnected status"); status.Host != "test" || status.Err != nil {
+		t.Errorf("expected host test to reconnect, got %+v", status)
+	}
+	receive(t, l.Ready(), "the listener to be ready")
+	if cont := receive(t, l.newConts, "the listed container"); cont.ID != fakeID("i") {
+		t.Errorf("expected the container to be listed once connected, got %s", cont.ID)
+	}
+}
+
+func TestUpdateStatsBarCharts(t *testing.T) {
+	cpuChart, memChart := updateStatsBarCharts([]ContainerStats{
+		{CPUPercent: 10, MemPercent: 85},
+		{CPUPercent: 96, MemPercent: 5},
+		{CPUPercent: 50, MemPercent: 50},
+	})
+
+	if want := []string{"3", "2", "1"}; !reflect.DeepEqual(cpuChart.DataLabels, want) {
+		t.Errorf("bars should be numbered down from the count, got %v", cpuChart.DataLabels)
+	}
+	if want := []float64{10, 96, 50}; !reflect.DeepEqual(cpuChart.Data, want) {
+		t.Errorf("unexpected cpu data %v", cpuChart.Data)
+	}
+	if want := []alertLevel{NormalLevel, CriticalLevel, NormalLevel}; !reflect.DeepEqual(cpuChart.Levels, want) {
+		t.Errorf("unexpected cpu levels %v", cpuChart.Levels)
+	}
+	if want := []alertLevel{WarningLevel, NormalLevel, NormalLevel}; !reflect.DeepEqual(memChart.Levels, want) {
+		t.Errorf("unexpected memory levels %v", memChart.Levels)
+	}
+
+	offset := cpuChart.Offset(2)
+	if len(offset.Data) != 1 || offset.DataLabels[0] != "1" || offset.Levels[0] != NormalLevel {
+		t.Errorf("offset should keep the last bar, got %+v", offset)
+	}
+	if empty := cpuChart.Offset(10); len(empty.Data) != 0 || len(empty.Levels) != 0 {
+		t.Errorf("offset past the end should be empty, got %+v", empty)
+	}
+
+	// idle containers can't be scaled to
+	idle, _ := updateStatsBarCharts([]ContainerStats{{}, {}})
+	chart := createBarChart()
+	idle.UpdateBarChart(chart)
+	if chart.MaxVal != 1 {
+		t.Errorf("expected idle bars to be scaled to 1, got %v", chart.MaxVal)
+	}
+}
+
+func TestCalculateCPUPercent(t *testing.T) {
+	for _, test := range []struct {
+		name              string
+		total, preTotal   uint64
+		system, preSystem uint64
+		cpus              uint64
+		want              float64
+	}{
+		{"one of two cpus", 500, 0, 1000, 0, 2, 100},
+		{"idle", 100, 100, 2000, 1000, 4, 0},
+		{"no system time", 200, 100, 1000, 1000, 1, 0},
+		{"quarter of one cpu", 350, 100, 2000, 1000, 1, 25},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var stats goDocker.Stats
+			stats.CPUStats.CPUUsage.TotalUsage = test.total
+			stats.PreCPUStats.CPUUsage.TotalUsage = test.preTotal
+			stats.CPUStats.SystemCPUUsage = test.system
+			stats.PreCPUStats.SystemCPUUsage = test.preSystem
+			stats.CPUStats.OnlineCPUs = test.cpus
+			if got := calculateCPUPercent(&stats); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeStatsOrdersByHost(t *testing.T) {
+	merged := mergeStats(map[string]StatsMsg{
+		"b": {Host: "b", Containers: []ContainerStats{{ID: "2"}}},
+		"a": {Host: "a", Containers: []ContainerStats{{ID: "1"}}},
+	})
+	if len(merged.Containers) != 2 || merged.Containers[0].ID != "1" || merged.Containers[1].ID != "2" {
+		t.Errorf("expected host a's stats first, got %+v", merged.Containers)
+	}
+}
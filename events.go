@@ -0,0 +1,165 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+// entries kept in the event log
+const maxEventLogEntries = 1000
+
+// eventLogEntry is a docker event as the event log shows it
+type eventLogEntry struct {
+	Time   time.Time
+	Host   string
+	Type   string
+	Action string
+	ID     string
+	Name   string
+}
+
+func newEventLogEntry(host string, e *goDocker.APIEvents) eventLogEntry {
+	entry := eventLogEntry{
+		Time:   time.Now(),
+		Host:   host,
+		Type:   e.Type,
+		Action: e.Action,
+		ID:     e.Actor.ID,
+		Name:   e.Actor.Attributes["name"],
+	}
+	if e.TimeNano != 0 {
+		entry.Time = time.Unix(0, e.TimeNano)
+	} else if e.Time != 0 {
+		entry.Time = time.Unix(e.Time, 0)
+	}
+	if len(entry.Action) == 0 {
+		entry.Action = e.Status
+	}
+	if len(entry.ID) == 0 {
+		entry.ID = e.ID
+	}
+	return entry
+}
+
+// eventLogTypes are the event types the log can be narrowed down to, the empty type shows them all
+var eventLogTypes = []string{"", "container", "image", "network", "volume"}
+
+// eventAlertLevels color events that stop or endanger a container
+var eventAlertLevels = map[string]alertLevel{
+	"die":                      CriticalLevel,
+	"kill":                     CriticalLevel,
+	"oom":                      CriticalLevel,
+	"destroy":                  CriticalLevel,
+	"health_status: unhealthy": CriticalLevel,
+	"stop":                     WarningLevel,
+	"pause":                    WarningLevel,
+	"restart":                  WarningLevel,
+	"delete":                   WarningLevel,
+	"untag":                    WarningLevel,
+	"disconnect":               WarningLevel,
+	"health_status: starting":  WarningLevel,
+}
+
+// line formats the entry as a row of the event log
+func (entry eventLogEntry) line() string {
+	var (
+		id    = entry.ID
+		parts = []string{entry.Time.Format("15:04:05")}
+	)
+	if len(id) == 64 {
+		id = id[:12]
+	}
+	if len(entry.Host) > 0 {
+		parts = append(parts, entry.Host)
+	}
+	parts = append(parts, entry.Type, entry.Action)
+	if len(entry.Name) > 0 && entry.Name != entry.ID {
+		parts = append(parts, entry.Name, "("+id+")")
+	} else {
+		parts = append(parts, id)
+	}
+	return highlighted(plainText(strings.Join(parts, " ")), eventAlertLevels[entry.Action])
+}
+
+// filterEvents returns the entries of the given type, all of them if it is empty
+func filterEvents(entries []eventLogEntry, eventType string) []eventLogEntry {
+	if len(eventType) == 0 {
+		return entries
+	}
+	var filtered []eventLogEntry
+	for _, entry := range entries {
+		if entry.Type == eventType {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// eventLogView is the model's subscriber for the event log screen
+type eventLogView struct {
+	m          *model
+	view       *view
+	scrollBack int
+	typeIndex  int
+}
+
+func newEventLogView(m *model, v *view) *eventLogView {
+	return &eventLogView{m: m, view: v}
+}
+
+func (el *eventLogView) handle(e event) {
+	switch e := e.(type) {
+	case screenChanged:
+		if e.Screen == EventScreen {
+			el.render()
+		}
+	case eventLogged:
+		if el.m.screen != EventScreen {
+			return
+		}
+		if el.scrollBack > 0 && len(filterEvents([]eventLogEntry{e.Entry}, eventLogTypes[el.typeIndex])) > 0 {
+			// keep the same entries in view
+			el.scroll(1)
+		} else {
+			el.render()
+		}
+	case keyPressed:
+		if el.m.screen != EventScreen {
+			return
+		}
+		switch e.Input.Event {
+		case KeyShiftE:
+			el.m.SetScreen(ContainerScreen)
+		case KeyArrowUp:
+			el.scroll(1)
+		case KeyArrowDown:
+			el.scroll(-1)
+		case KeyPageUp:
+			el.scroll(el.view.EventList.Inner.Dy())
+		case KeyPageDown:
+			el.scroll(-el.view.EventList.Inner.Dy())
+		case KeySpace:
+			// cycle through the event types
+			el.typeIndex = (el.typeIndex + 1) % len(eventLogTypes)
+			el.scrollBack = 0
+			el.render()
+		}
+	}
+}
+
+func (el *eventLogView) render() {
+	el.view.RenderEvents(el.m.eventLog, eventLogTypes[el.typeIndex], el.scrollBack)
+}
+
+func (el *eventLogView) scroll(entries int) {
+	el.scrollBack += entries
+	if oldest := len(filterEvents(el.m.eventLog, eventLogTypes[el.typeIndex])) - 1; el.scrollBack > oldest {
+		el.scrollBack = oldest
+	}
+	if el.scrollBack < 0 {
+		el.scrollBack = 0
+	}
+	el.render()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+func TestEventLogLineKeepsMarkupAsText(t *testing.T) {
+	entry := newEventLogEntry("", &goDocker.APIEvents{
+		Type:     "container",
+		Action:   "die",
+		Actor:    goDocker.APIActor{ID: fakeID("a"), Attributes: map[string]string{"name": "web](fg:green)[x"}},
+		TimeNano: time.Date(2024, 5, 1, 14, 3, 9, 0, time.Local).UnixNano(),
+	})
+	if got, want := entry.line(), "[14:03:09 container die web)(fg:green)(x (a00000000000)](fg:critical)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	entry.Action = "start"
+	if got, want := entry.line(), "14:03:09 container start web)(fg:green)(x (a00000000000)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
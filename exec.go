@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	. "github.com/byrnedo/dockdash/logger"
+	goDocker "github.com/fsouza/go-dockerclient"
+	"github.com/moby/term"
+)
+
+// tried in order, the first one that exists in the container is used
+var execShells = []string{"bash", "sh"}
+
+// exit codes from a shell that could not be found or run
+const (
+	execNotExecutable = 126
+	execNotFound      = 127
+)
+
+// ExecShell runs an interactive shell in the container, attached to the user's terminal.
+// The dashboard has to be closed beforehand.
+func (sl *StatsListener) ExecShell(id string) error {
+	inFd, _ := term.GetFdInfo(os.Stdin)
+	state, err := term.SetRawTerminal(inFd)
+	if err != nil {
+		return err
+	}
+	defer term.RestoreTerminal(inFd, state)
+
+	for _, shell := range execShells {
+		exitCode, err := sl.execShell(id, shell, inFd)
+		if err != nil {
+			return err
+		}
+		if exitCode != execNotExecutable && exitCode != execNotFound {
+			return nil
+		}
+		Info.Println(shell, "not found in", id)
+	}
+	return fmt.Errorf("no shell found, tried %v", execShells)
+}
+
+func (sl *StatsListener) execShell(id string, shell string, inFd uintptr) (exitCode int, err error) {
+	exec, err := sl.DockerClient.CreateExec(goDocker.CreateExecOptions{
+		Container:    id,
+		Cmd:          []string{shell},
+		Env:          []string{"TERM=" + os.Getenv("TERM")},
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	waiter, err := sl.DockerClient.StartExecNonBlocking(exec.ID, goDocker.StartExecOptions{
+		InputStream:  os.Stdin,
+		OutputStream: os.Stdout,
+		ErrorStream:  os.Stderr,
+		Tty:          true,
+		RawTerminal:  true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if size, err := term.GetWinsize(inFd); err == nil {
+		if err := sl.DockerClient.ResizeExecTTY(exec.ID, int(size.Height), int(size.Width)); err != nil {
+			Error.Println("Failed to resize exec tty:", err)
+		}
+	}
+
+	if err := waiter.Wait(); err != nil {
+		return 0, err
+	}
+
+	inspect, err := sl.DockerClient.InspectExec(exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
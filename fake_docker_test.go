@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+var errFakeNotSupported = errors.New("not supported by the fake docker daemon")
+
+// fakeDocker is an in-memory daemon. Tests script it with the create, start, die, destroy and sendStats helpers,
+// which update its containers and send the matching events to the listeners.
+type fakeDocker struct {
+	mu         sync.Mutex
+	containers map[string]*goDocker.Container
+	images     []goDocker.APIImages
+	listeners  []chan<- *goDocker.APIEvents
+	// the stats streams open per container
+	statsStreams map[string]goDocker.StatsOptions
+	// streamOpened gets the id of each container a stats stream is opened for
+	streamOpened chan string
+	// actions records the container actions called, as "stop <id>"
+	actions []string
+	pingErr error
+}
+
+func newFakeDocker() *fakeDocker {
+	return &fakeDocker{
+		containers:   make(map[string]*goDocker.Container),
+		statsStreams: make(map[string]goDocker.StatsOptions),
+		streamOpened: make(chan string, 100),
+	}
+}
+
+// fakeID pads a short id out to the 64 characters docker uses
+func fakeID(short string) string {
+	return short + strings.Repeat("0", 64-len(short))
+}
+
+func (d *fakeDocker) emit(status string, id string) {
+	d.mu.Lock()
+	listeners := append([]chan<- *goDocker.APIEvents{}, d.listeners...)
+	name := ""
+	if cont, ok := d.containers[id]; ok {
+		name = strings.TrimLeft(cont.Name, "/")
+	}
+	d.mu.Unlock()
+
+	e := &goDocker.APIEvents{
+		Status:   status,
+		ID:       id,
+		Type:     "container",
+		Action:   status,
+		Actor:    goDocker.APIActor{ID: id, Attributes: map[string]string{"name": name}},
+		TimeNano: time.Now().UnixNano(),
+	}
+	for _, listener := range listeners {
+		listener <- e
+	}
+}
+
+func (d *fakeDocker) create(id string, name string, image string) {
+	d.mu.Lock()
+	d.containers[id] = &goDocker.Container{
+		ID:              id,
+		Name:            "/" + name,
+		Created:         time.Now(),
+		Config:          &goDocker.Config{Image: image, Labels: map[string]string{}},
+		HostConfig:      &goDocker.HostConfig{},
+		NetworkSettings: &goDocker.NetworkSettings{},
+	}
+	d.mu.Unlock()
+	d.emit("create", id)
+}
+
+func (d *fakeDocker) start(id string) {
+	d.mu.Lock()
+	cont := d.containers[id]
+	cont.State.Running = true
+	cont.State.StartedAt = time.Now()
+	d.mu.Unlock()
+	d.emit("start", id)
+}
+
+// die stops the container with the exit code, ending its stats stream
+func (d *fakeDocker) die(id string, exitCode int) {
+	d.mu.Lock()
+	cont := d.containers[id]
+	cont.State.Running = false
+	cont.State.ExitCode = exitCode
+	cont.State.FinishedAt = time.Now()
+	d.mu.Unlock()
+	d.emit("die", id)
+}
+
+func (d *fakeDocker) destroy(id string) {
+	d.emit("destroy", id)
+	d.mu.Lock()
+	delete(d.containers, id)
+	d.mu.Unlock()
+}
+
+func (d *fakeDocker) rename(id string, name string) {
+	d.mu.Lock()
+	d.containers[id].Name = "/" + name
+	d.mu.Unlock()
+	d.emit("rename", id)
+}
+
+// sendStats sends a sample on the container's stats stream, returning false if no stream is open
+func (d *fakeDocker) sendStats(id string, stats *goDocker.Stats) bool {
+	d.mu.Lock()
+	opts, ok := d.statsStreams[id]
+	d.mu.Unlock()
+	if !ok {
+		return false
+	}
+	select {
+	case opts.Stats <- stats:
+		return true
+	case <-opts.Done:
+		return false
+	}
+}
+
+// closeEvents ends the event stream, like a daemon restart does
+func (d *fakeDocker) closeEvents() {
+	d.mu.Lock()
+	listeners := d.listeners
+	d.listeners = nil
+	d.mu.Unlock()
+	for _, listener := range listeners {
+		close(listener)
+	}
+}
+
+func (d *fakeDocker) Ping() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pingErr
+}
+
+func (d *fakeDocker) AddEventListener(listener chan<- *goDocker.APIEvents) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.listeners = append(d.listeners, listener)
+	return nil
+}
+
+func (d *fakeDocker) RemoveEventListener(listener chan *goDocker.APIEvents) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, l := range d.listeners {
+		if l == listener {
+			d.listeners = append(d.listeners[:i], d.listeners[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (d *fakeDocker) ListContainers(opts goDocker.ListContainersOptions) (listed []goDocker.APIContainers, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range sortedKeys(d.containers) {
+		cont := d.containers[id]
+		state := "created"
+		if cont.State.Running {
+			state = "running"
+		} else if !cont.State.FinishedAt.IsZero() {
+			state = "exited"
+		}
+		if state != "running" && !opts.All {
+			continue
+		}
+		listed = append(listed, goDocker.APIContainers{ID: id, Names: []string{cont.Name}, Image: cont.Config.Image, State: state})
+	}
+	return
+}
+
+func (d *fakeDocker) InspectContainer(id string) (*goDocker.Container, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	cont, ok := d.containers[id]
+	if !ok {
+		return nil, &goDocker.NoSuchContainer{ID: id}
+	}
+	copied := *cont
+	return &copied, nil
+}
+
+func (d *fakeDocker) ListImages(opts goDocker.ListImagesOptions) ([]goDocker.APIImages, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]goDocker.APIImages{}, d.images...), nil
+}
+
+func (d *fakeDocker) Stats(opts goDocker.StatsOptions) error {
+	defer close(opts.Stats)
+	d.mu.Lock()
+	d.statsStreams[opts.ID] = opts
+	d.mu.Unlock()
+	d.streamOpened <- opts.ID
+
+	select {
+	case <-opts.Done:
+	case <-opts.Context.Done():
+	}
+	d.mu.Lock()
+	delete(d.statsStreams, opts.ID)
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *fakeDocker) Logs(opts goDocker.LogsOptions) error {
+	return errFakeNotSupported
+}
+
+func (d *fakeDocker) record(action string, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.containers[id]; !ok {
+		return &goDocker.NoSuchContainer{ID: id}
+	}
+	d.actions = append(d.actions, action+" "+id)
+	return nil
+}
+
+func (d *fakeDocker) StartContainer(id string, hostConfig *goDocker.HostConfig) error {
+	return d.record("start", id)
+}
+
+func (d *fakeDocker) StopContainer(id string, timeout uint) error {
+	return d.record("stop", id)
+}
+
+func (d *fakeDocker) RestartContainer(id string, timeout uint) error {
+	return d.record("restart", id)
+}
+
+func (d *fakeDocker) KillContainer(opts goDocker.KillContainerOptions) error {
+	return d.record("kill", opts.ID)
+}
+
+func (d *fakeDocker) PauseContainer(id string) error {
+	return d.record("pause", id)
+}
+
+func (d *fakeDocker) UnpauseContainer(id string) error {
+	return d.record("unpause", id)
+}
+
+func (d *fakeDocker) CreateExec(opts goDocker.CreateExecOptions) (*goDocker.Exec, error) {
+	return nil, errFakeNotSupported
+}
+
+func (d *fakeDocker) StartExecNonBlocking(id string, opts goDocker.StartExecOptions) (goDocker.CloseWaiter, error) {
+	return nil, errFakeNotSupported
+}
+
+func (d *fakeDocker) ResizeExecTTY(id string, height int, width int) error {
+	return errFakeNotSupported
+}
+
+func (d *fakeDocker) InspectExec(id string) (*goDocker.ExecInspect, error) {
+	return nil, errFakeNotSupported
+}
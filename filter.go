@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// containerFilter is a docker style key=value filter. Filters without a key search the name and image.
+type containerFilter struct {
+	key   string
+	value string
+}
+
+var filterKeys = []string{"name", "label", "ancestor", "status"}
+
+func parseFilter(expr string) (containerFilter, error) {
+	key, value, found := strings.Cut(expr, "=")
+	if !found {
+		return containerFilter{value: expr}, nil
+	}
+	for _, filterKey := range filterKeys {
+		if key == filterKey {
+			return containerFilter{key, value}, nil
+		}
+	}
+	return containerFilter{}, fmt.Errorf("unknown filter %q, expected one of %s", key, strings.Join(filterKeys, ", "))
+}
+
+func (f containerFilter) matches(cont container) bool {
+	switch f.key {
+	case "name":
+		return strings.Contains(cont.shortName(), f.value)
+	case "label":
+		if cont.Config == nil {
+			return false
+		}
+		labelKey, labelValue, hasValue := strings.Cut(f.value, "=")
+		value, ok := cont.Config.Labels[labelKey]
+		return ok && (!hasValue || value == labelValue)
+	case "ancestor":
+		image := cont.imageName()
+		return image == f.value ||
+			strings.HasPrefix(image, f.value+":") ||
+			strings.HasPrefix(strings.TrimPrefix(cont.Image, "sha256:"), strings.TrimPrefix(f.value, "sha256:"))
+	case "status":
+		return cont.State.StateString() == f.value
+	default:
+		search := strings.ToLower(f.value)
+		return strings.Contains(strings.ToLower(cont.shortName()), search) ||
+			strings.Contains(strings.ToLower(cont.imageName()), search)
+	}
+}
+
+// containerFilters match like docker ps: filters with the same key are or'ed, different keys are and'ed
+type containerFilters []containerFilter
+
+// parseFilters parses space separated filter expressions, as typed into the search prompt
+func parseFilters(expr string) (filters containerFilters, err error) {
+	for _, field := range strings.Fields(expr) {
+		filter, err := parseFilter(field)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return
+}
+
+func (fs containerFilters) hasStatus() bool {
+	for _, f := range fs {
+		if f.key == "status" {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs containerFilters) matches(cont container) bool {
+	var (
+		seen    = make(map[string]bool)
+		matched = make(map[string]bool)
+	)
+	for _, f := range fs {
+		seen[f.key] = true
+		if !matched[f.key] && f.matches(cont) {
+			matched[f.key] = true
+		}
+	}
+	for key := range seen {
+		if !matched[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func (fs containerFilters) apply(cm containerMap) containerMap {
+	if len(fs) == 0 {
+		return cm
+	}
+	filtered := make(containerMap, len(cm))
+	for id, cont := range cm {
+		if fs.matches(cont) {
+			filtered[id] = cont
+		}
+	}
+	return filtered
+}
+
+// stringsFlag collects every value of a repeated flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
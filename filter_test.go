@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiltersMatchContainersWithoutConfig(t *testing.T) {
+	cont := testContainer("a", "web", "nginx:1.25", true, time.Hour, nil)
+	cont.Image = "sha256:" + fakeID("f")
+	cont.Config = nil
+
+	for _, tc := range []struct {
+		expr    string
+		matches bool
+	}{
+		{"web", true},
+		{"nginx", false},
+		{"name=we", true},
+		{"label=tier", false},
+		{"ancestor=nginx", false},
+		{"ancestor=ffff", false},
+		{"ancestor=f000", true},
+		{"status=running", true},
+	} {
+		filters, err := parseFilters(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filters.matches(cont); got != tc.matches {
+			t.Errorf("%s: expected a match to be %v, got %v", tc.expr, tc.matches, got)
+		}
+	}
+}
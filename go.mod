@@ -3,9 +3,12 @@ module github.com/byrnedo/dockdash
 go 1.18
 
 require (
+	github.com/docker/go-units v0.4.0
 	github.com/fsouza/go-dockerclient v1.7.11
 	github.com/gizak/termui/v3 v3.1.0
+	github.com/moby/term v0.0.0-20210619224110-3f7ff695adc6
 	github.com/ogier/pflag v0.0.2-0.20150809183316-6f7159c3154e
+	gopkg.in/yaml.v3 v3.0.0
 )
 
 require (
@@ -14,14 +17,12 @@ require (
 	github.com/containerd/containerd v1.6.1 // indirect
 	github.com/docker/docker v20.10.3-0.20220208084023-a5c757555091+incompatible // indirect
 	github.com/docker/go-connections v0.4.0 // indirect
-	github.com/docker/go-units v0.4.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/klauspost/compress v1.11.13 // indirect
 	github.com/mattn/go-runewidth v0.0.2 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/moby/sys/mount v0.2.0 // indirect
 	github.com/moby/sys/mountinfo v0.5.0 // indirect
-	github.com/moby/term v0.0.0-20210619224110-3f7ff695adc6 // indirect
 	github.com/morikuni/aec v1.0.0 // indirect
 	github.com/nsf/termbox-go v0.0.0-20190121233118-02980233997d // indirect
 	github.com/opencontainers/go-digest v1.0.0 // indirect
@@ -30,5 +31,4 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/sirupsen/logrus v1.8.1 // indirect
 	golang.org/x/sys v0.0.0-20211216021012-1d35b9e2eb4e // indirect
-	gopkg.in/yaml.v3 v3.0.0 // indirect
 )
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// labels set by docker compose on the containers it creates
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+func (cont container) label(key string) string {
+	if cont.Config == nil {
+		return ""
+	}
+	return cont.Config.Labels[key]
+}
+
+func (cont container) composeProject() string {
+	return cont.label(composeProjectLabel)
+}
+
+// serviceName is the compose service the container runs, falling back to the container name
+func (cont container) serviceName() string {
+	if service := cont.label(composeServiceLabel); len(service) > 0 {
+		return service
+	}
+	return cont.shortName()
+}
+
+type composeProject struct {
+	name       string
+	host       string
+	containers containerSlice
+	collapsed  bool
+}
+
+// projectKey tells projects of the same name on different hosts apart
+func projectKey(host string, name string) string {
+	return host + "/" + name
+}
+
+func (p *composeProject) key() string {
+	return projectKey(p.host, p.name)
+}
+
+func (p *composeProject) title() string {
+	title := p.name
+	if len(title) == 0 {
+		title = "(no project)"
+	}
+	if len(p.host) > 0 {
+		title += "@" + p.host
+	}
+	return title
+}
+
+// totals adds up the stats of the project's running containers
+func (p *composeProject) totals(stats map[string]ContainerStats) (cpu float64, mem float64) {
+	for _, cont := range p.containers {
+		if cont.running() {
+			cpu += stats[cont.ID].CPUPercent
+			mem += stats[cont.ID].MemPercent
+		}
+	}
+	return
+}
+
+// listRow is one line of the container list, either a container or, when grouping, a compose project header
+type listRow struct {
+	cont    container
+	project *composeProject
+}
+
+func (r listRow) header() bool {
+	return r.project != nil
+}
+
+type listRows []listRow
+
+func flatRows(cs containerSlice) listRows {
+	rows := make(listRows, len(cs))
+	for i, cont := range cs {
+		rows[i] = listRow{cont: cont}
+	}
+	return rows
+}
+
+// groupedRows puts the containers under a header per compose project, keeping their order within a project.
+// Projects are listed by name, followed by the containers not started by compose.
+func groupedRows(cs containerSlice, collapsed map[string]bool) (rows listRows) {
+	var (
+		byName   = make(map[string]*composeProject)
+		projects []*composeProject
+	)
+	for _, cont := range cs {
+		key := projectKey(cont.host, cont.composeProject())
+		project, ok := byName[key]
+		if !ok {
+			project = &composeProject{name: cont.composeProject(), host: cont.host, collapsed: collapsed[key]}
+			byName[key] = project
+			projects = append(projects, project)
+		}
+		project.containers = append(project.containers, cont)
+	}
+
+	sort.Slice(projects, func(i int, j int) bool {
+		a, b := projects[i], projects[j]
+		if (len(a.name) == 0) != (len(b.name) == 0) {
+			return len(b.name) == 0
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.host < b.host
+	})
+
+	for _, project := range projects {
+		rows = append(rows, listRow{project: project})
+		if project.collapsed {
+			continue
+		}
+		for _, cont := range project.containers {
+			rows = append(rows, listRow{cont: cont})
+		}
+	}
+	return
+}
+
+// atOffset returns the row shown at the top of the list for the given offset
+func (rows listRows) atOffset(offset int) (row listRow, ok bool) {
+	if offset < 0 || offset >= len(rows) {
+		return
+	}
+	return rows[offset], true
+}
+
+// selected returns the container at the top of the list, if that row isn't a project header
+func (rows listRows) selected(offset int) (cont container, ok bool) {
+	row, ok := rows.atOffset(offset)
+	if !ok || row.header() {
+		return container{}, false
+	}
+	return row.cont, true
+}
+
+// containers returns the containers shown in the list, in list order, which is the order of the chart bars
+func (rows listRows) containers() (cs containerSlice) {
+	for _, row := range rows {
+		if !row.header() {
+			cs = append(cs, row.cont)
+		}
+	}
+	return
+}
+
+// headerIndex finds the row of a project's header
+func (rows listRows) headerIndex(key string) int {
+	for i, row := range rows {
+		if row.header() && row.project.key() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (rows listRows) namesAndInfo(offset int, infoType dockerInfoType, inspectMode bool, stats map[string]ContainerStats) ([]string, []string) {
+	var numRows = len(rows)
+	if offset > numRows {
+		offset = numRows - 1
+	}
+
+	var (
+		info          []string
+		numRowsSubset = numRows - offset
+		names         = make([]string, numRowsSubset)
+		numbers       = rows.numbers()
+		grouped       = numRows > 0 && rows[0].header()
+		hostWidth     = rows.containers().hostWidth()
+		nameStr       = ""
+	)
+
+	if !inspectMode {
+		info = make([]string, numRowsSubset)
+	}
+
+	for index, row := range rows {
+		if index < offset {
+			continue
+		}
+		if row.header() {
+			nameStr = row.project.headerName()
+			if inspectMode && index == offset {
+				names[index-offset] = "*" + nameStr
+				info = row.project.inspectInfo(stats)
+			} else {
+				names[index-offset] = " " + nameStr
+				if !inspectMode {
+					info[index-offset] = row.project.regularInfo(stats)
+				}
+			}
+			continue
+		}
+
+		cont := row.cont
+		nameStr = numbers[index] + ". "
+		if hostWidth > 0 {
+			nameStr += fmt.Sprintf("%-*s ", hostWidth, cont.host)
+		}
+		if grouped {
+			nameStr = "  " + nameStr + cont.ID[:12] + " " + cont.serviceName()
+		} else {
+			nameStr += cont.ID[:12] + " " + cont.shortName()
+		}
+		switch {
+		case cont.State.Restarting:
+			nameStr = highlighted(nameStr+" ("+cont.status()+")", cont.alertLevel(stats[cont.ID]))
+		case cont.running():
+			if notes := cont.runningNotes(); len(notes) > 0 {
+				nameStr += " " + notes
+			}
+			nameStr = highlighted(nameStr, cont.alertLevel(stats[cont.ID]))
+		default:
+			nameStr = greyedOut(nameStr + " (" + cont.status() + ")")
+		}
+
+		if inspectMode && index == offset {
+			names[index-offset] = "*" + nameStr
+			info = cont.inspectInfo(index, offset, infoType, stats[cont.ID])
+		} else {
+			names[index-offset] = " " + nameStr
+			if !inspectMode {
+				info[index-offset] = cont.regularInfo(index, offset, infoType, stats[cont.ID])
+				if !cont.running() {
+					info[index-offset] = greyedOut(info[index-offset])
+				}
+			}
+		}
+
+	}
+	return names, info
+}
+
+// numbers gives each row its chart number. Only running containers are numbered, so the numbers match the
+// stats charts, the others and project headers get a dash.
+func (rows listRows) numbers() []string {
+	var (
+		numbers      = make([]string, len(rows))
+		numRunning   = rows.containers().numRunning()
+		runningIndex = 0
+	)
+	for index, row := range rows {
+		if row.header() || !row.cont.running() {
+			numbers[index] = "-"
+			continue
+		}
+		numbers[index] = strconv.Itoa(numRunning - runningIndex)
+		runningIndex++
+	}
+	return numbers
+}
+
+func (p *composeProject) marker() string {
+	if p.collapsed {
+		return "▸"
+	}
+	return "▾"
+}
+
+func (p *composeProject) headerName() string {
+	return fmt.Sprintf("[%s %s (%d/%d up)](fg:project)", p.marker(), p.title(), p.containers.numRunning(), len(p.containers))
+}
+
+func (p *composeProject) regularInfo(stats map[string]ContainerStats) string {
+	cpu, mem := p.totals(stats)
+	return fmt.Sprintf("[CPU %.1f%%  MEM %.1f%%](fg:project)", cpu, mem)
+}
+
+// inspectInfo lists the project's services with their state
+func (p *composeProject) inspectInfo(stats map[string]ContainerStats) (info []string) {
+	cpu, mem := p.totals(stats)
+	info = append(info, fmt.Sprintf("CPU %.1f%%  MEM %.1f%%", cpu, mem))
+	for _, cont := range p.containers {
+		line := cont.serviceName() + ": " + cont.status()
+		if cont.running() {
+			line += fmt.Sprintf(", CPU %.1f%%, MEM %.1f%%", stats[cont.ID].CPUPercent, stats[cont.ID].MemPercent)
+		}
+		info = append(info, line)
+	}
+	return
+}
+
+// runningBefore counts the running containers above the row, which is how many chart bars to skip
+func (rows listRows) runningBefore(offset int) int {
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return rows[:offset].containers().numRunning()
+}
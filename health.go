@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// health statuses reported by docker for containers with a health check
+const (
+	healthStarting  = "starting"
+	healthHealthy   = "healthy"
+	healthUnhealthy = "unhealthy"
+)
+
+// healthEventPrefix starts the status of health events, followed by the new health, e.g. "health_status: healthy"
+const healthEventPrefix = "health_status"
+
+// health is the result of the container's health check, empty if it is stopped or has no health check
+func (cont container) health() string {
+	if !cont.running() || cont.State.Health.Status == "none" {
+		return ""
+	}
+	return cont.State.Health.Status
+}
+
+// healthSummary is the health as docker ps shows it after the uptime
+func (cont container) healthSummary() string {
+	switch health := cont.health(); health {
+	case "":
+		return ""
+	case healthStarting:
+		return "(health: starting)"
+	default:
+		return "(" + health + ")"
+	}
+}
+
+// healthInfo describes the health and the last check's output, for inspect mode
+func (cont container) healthInfo() (info []string) {
+	health := cont.health()
+	if len(health) == 0 {
+		return
+	}
+	line := "Health: " + health
+	if streak := cont.State.Health.FailingStreak; streak > 0 {
+		line += ", failed " + strconv.Itoa(streak) + " in a row"
+	}
+	info = append(info, line)
+
+	if checks := cont.State.Health.Log; len(checks) > 0 {
+		last := checks[len(checks)-1]
+		info = append(info, "Last check: "+last.End.Format(time.RubyDate)+", exit code "+strconv.Itoa(last.ExitCode))
+		for _, output := range strings.Split(strings.TrimRight(last.Output, "\n"), "\n") {
+			if len(output) > 0 {
+				info = append(info, "  "+output)
+			}
+		}
+	}
+	return
+}
+
+// alertLevel is the level to highlight the container at, unhealthy containers are critical whatever their usage
+func (cont container) alertLevel(stats ContainerStats) alertLevel {
+	if cont.health() == healthUnhealthy {
+		return CriticalLevel
+	}
+	return alertThresholds.level(stats)
+}
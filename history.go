@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// enough samples for the longest window at docker's one sample a second
+const historyLength = 15 * 60
+
+var historyWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+func windowName(window time.Duration) string {
+	return fmt.Sprintf("%dm", int(window.Minutes()))
+}
+
+type statsSample struct {
+	At  time.Time
+	CPU float64
+	Mem float64
+}
+
+// sampleRing keeps the latest samples of a container, overwriting the oldest once full
+type sampleRing struct {
+	samples []statsSample
+	start   int
+}
+
+func (r *sampleRing) add(sample statsSample) {
+	if len(r.samples) < historyLength {
+		r.samples = append(r.samples, sample)
+		return
+	}
+	r.samples[r.start] = sample
+	r.start = (r.start + 1) % historyLength
+}
+
+// since returns the samples taken after t, oldest first
+func (r *sampleRing) since(t time.Time) (samples []statsSample) {
+	for i := range r.samples {
+		sample := r.samples[(r.start+i)%len(r.samples)]
+		if sample.At.After(t) {
+			samples = append(samples, sample)
+		}
+	}
+	return
+}
+
+// statsHistory is written to by the stats routine and read by the ui
+type statsHistory struct {
+	mutex sync.RWMutex
+	rings map[string]*sampleRing
+}
+
+func newStatsHistory() *statsHistory {
+	return &statsHistory{rings: make(map[string]*sampleRing)}
+}
+
+func (h *statsHistory) Add(id string, sample statsSample) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	ring, ok := h.rings[id]
+	if !ok {
+		ring = &sampleRing{}
+		h.rings[id] = ring
+	}
+	ring.add(sample)
+}
+
+func (h *statsHistory) Since(id string, t time.Time) []statsSample {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if ring, ok := h.rings[id]; ok {
+		return ring.since(t)
+	}
+	return nil
+}
+
+func (h *statsHistory) Remove(id string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.rings, id)
+}
+
+// downsample squeezes values into width points, keeping the peak of each group so short spikes stay visible
+func downsample(values []float64, width int) []float64 {
+	if width <= 0 || len(values) <= width {
+		return values
+	}
+	var (
+		groupSize = (len(values) + width - 1) / width
+		points    = make([]float64, 0, width)
+	)
+	for i := 0; i < len(values); i += groupSize {
+		peak := values[i]
+		for j := i + 1; j < i+groupSize && j < len(values); j++ {
+			if values[j] > peak {
+				peak = values[j]
+			}
+		}
+		points = append(points, peak)
+	}
+	return points
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+// dockerHost is a daemon to monitor. Hosts are only named when more than one is monitored.
+type dockerHost struct {
+	Name     string
+	Endpoint string
+	tlsOptions
+}
+
+// tlsOptions turn on tls when any of them are set. The ca is only used to verify the daemon's certificate.
+type tlsOptions struct {
+	TLSCert   string
+	TLSKey    string
+	TLSCA     string
+	TLSVerify bool
+}
+
+func (o tlsOptions) enabled() bool {
+	return len(o.TLSCert) > 0 || len(o.TLSKey) > 0 || len(o.TLSCA) > 0 || o.TLSVerify
+}
+
+func (o tlsOptions) validate() error {
+	if o.TLSVerify && len(o.TLSCA) == 0 {
+		return fmt.Errorf("--tls-verify needs --tls-ca")
+	}
+	if (len(o.TLSCert) > 0) != (len(o.TLSKey) > 0) {
+		return fmt.Errorf("--tls-cert and --tls-key have to be given together")
+	}
+	for _, file := range []string{o.TLSCert, o.TLSKey, o.TLSCA} {
+		if len(file) == 0 {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseHost reads a [name=]endpoint expression, naming the host after the endpoint's hostname if no name is given
+func parseHost(expr string) dockerHost {
+	name, endpoint, found := strings.Cut(expr, "=")
+	if !found || strings.ContainsAny(name, ":/") {
+		return dockerHost{Name: endpointName(expr), Endpoint: expr}
+	}
+	return dockerHost{Name: name, Endpoint: endpoint}
+}
+
+func endpointName(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "unix" || u.Scheme == "npipe" || len(u.Hostname()) == 0 {
+		return "local"
+	}
+	return u.Hostname()
+}
+
+// parseHosts parses the endpoints given by --docker-endpoint or the config file, an empty list means the single
+// host set up in the environment. The tls options apply to every host without tls settings of its own.
+func parseHosts(endpoints []endpointConfig, tls tlsOptions) ([]dockerHost, error) {
+	if err := tls.validate(); err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return []dockerHost{{tlsOptions: tls}}, nil
+	}
+
+	var (
+		hosts = make([]dockerHost, len(endpoints))
+		seen  = make(map[string]bool)
+	)
+	for i, ec := range endpoints {
+		if len(ec.Name) > 0 {
+			hosts[i] = dockerHost{Name: ec.Name, Endpoint: ec.Endpoint}
+		} else {
+			hosts[i] = parseHost(ec.Endpoint)
+		}
+		hosts[i].tlsOptions = tls
+		if own := ec.tls(); own.enabled() {
+			if err := own.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", hosts[i].Name, err)
+			}
+			hosts[i].tlsOptions = own
+		}
+		if seen[hosts[i].Name] {
+			return nil, fmt.Errorf("host name %q is used twice, name endpoints with name=endpoint", hosts[i].Name)
+		}
+		seen[hosts[i].Name] = true
+	}
+	if len(hosts) == 1 {
+		// a single host isn't named
+		hosts[0].Name = ""
+	}
+	return hosts, nil
+}
+
+// connect creates a client for the host, tunnelling over ssh for ssh:// endpoints. If the tunnel can't be opened
+// the client and tunnel are returned with the error, for the listener to open the tunnel again as it retries.
+func (h dockerHost) connect() (*goDocker.Client, *sshTunnel, error) {
+	endpoint := h.Endpoint
+	if len(endpoint) == 0 {
+		if !h.enabled() {
+			client, err := goDocker.NewClientFromEnv()
+			return client, nil, err
+		}
+		if endpoint = os.Getenv("DOCKER_HOST"); len(endpoint) == 0 {
+			return nil, nil, fmt.Errorf("the tls options need --docker-endpoint or DOCKER_HOST")
+		}
+	}
+
+	var tunnel *sshTunnel
+	if strings.HasPrefix(endpoint, "ssh://") {
+		var err error
+		if tunnel, err = newSSHTunnel(endpoint); err != nil {
+			return nil, nil, err
+		}
+		endpoint = tunnel.endpoint()
+	}
+
+	var (
+		client *goDocker.Client
+		err    error
+	)
+	if h.enabled() {
+		ca := ""
+		if h.TLSVerify {
+			ca = h.TLSCA
+		}
+		client, err = goDocker.NewTLSClient(endpoint, h.TLSCert, h.TLSKey, ca)
+	} else {
+		client, err = goDocker.NewClient(endpoint)
+	}
+	if err != nil {
+		if tunnel != nil {
+			tunnel.Close()
+		}
+		return nil, nil, err
+	}
+	if tunnel != nil {
+		// asks for a password or passphrase if it needs one, before the dashboard has the terminal
+		err = tunnel.open()
+	}
+	return client, tunnel, err
+}
+
+// listenerSet is a stats listener per docker host, all sending on the same events channel
+type listenerSet []*StatsListener
+
+func (ls listenerSet) forHost(host string) *StatsListener {
+	for _, sl := range ls {
+		if sl.Host == host {
+			return sl
+		}
+	}
+	return ls[0]
+}
+
+func (ls listenerSet) hostNames() (names []string) {
+	for _, sl := range ls {
+		if len(sl.Host) > 0 {
+			names = append(names, sl.Host)
+		}
+	}
+	return
+}
+
+// Open opens every listener at once, returning when they have all listed their containers.
+// Hosts that fail to connect don't stop the others from opening, their errors are returned together.
+func (ls listenerSet) Open(events chan<- event) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(ls))
+	)
+	for i, sl := range ls {
+		wg.Add(1)
+		go func(i int, sl *StatsListener) {
+			defer wg.Done()
+			err := sl.Open(events)
+			if err != nil && len(sl.Host) > 0 {
+				err = fmt.Errorf("%s: %w", sl.Host, err)
+			}
+			errs[i] = err
+		}(i, sl)
+	}
+	wg.Wait()
+	return joinErrors(errs)
+}
+
+// joinErrors puts the non nil errors on one line, for the info bar
+func joinErrors(errs []error) error {
+	var msgs []string
+	for _, err := range errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// Ready is closed once every listener is ready, it has to be called after Open
+func (ls listenerSet) Ready() <-chan struct{} {
+	ready := make(chan struct{})
+	go func() {
+		for _, sl := range ls {
+			<-sl.Ready()
+		}
+		close(ready)
+	}()
+	return ready
+}
+
+// ListStopped has every listener list the stopped containers too
+func (ls listenerSet) ListStopped() {
+	for _, sl := range ls {
+		sl.ListStopped()
+	}
+}
+
+func (ls listenerSet) Close() {
+	for _, sl := range ls {
+		sl.Close()
+	}
+}
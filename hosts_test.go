@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEndpointsWithTheirOwnTLS(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"cert.pem", "key.pem", "ca.pem"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "config.yaml")
+	config := strings.ReplaceAll(`endpoints:
+  - local=unix:///var/run/docker.sock
+  - name: prod
+    endpoint: tcp://prod:2376
+    tls-cert: DIR/cert.pem
+    tls-key: DIR/key.pem
+    tls-ca: DIR/ca.pem
+    tls-verify: true
+`, "DIR", dir)
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts, err := parseHosts(cfg.Endpoints, tlsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 2 || hosts[0].Name != "local" || hosts[1].Name != "prod" {
+		t.Fatalf("expected hosts local and prod, got %+v", hosts)
+	}
+	if hosts[0].enabled() {
+		t.Errorf("the local socket shouldn't use tls, got %+v", hosts[0].tlsOptions)
+	}
+	if !hosts[1].TLSVerify || hosts[1].TLSCA != filepath.Join(dir, "ca.pem") {
+		t.Errorf("expected prod to verify against its ca, got %+v", hosts[1].tlsOptions)
+	}
+
+	if _, err := parseHosts([]endpointConfig{{Name: "prod", Endpoint: "tcp://prod:2376", TLSVerify: true}}, tlsOptions{}); err == nil {
+		t.Error("expected an error verifying without a ca")
+	}
+
+	if err := os.WriteFile(path, []byte("endpoints:\n  - endpoint: tcp://prod:2376\n    tls-certificate: x\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path, true); err == nil {
+		t.Error("expected an error for an unknown endpoint field")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/byrnedo/dockdash/logger"
+	units "github.com/docker/go-units"
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+var imageHeaders = []string{"Repository:Tag", "ID", "Size", "Created", "Containers"}
+
+// image is a local image of one of the docker hosts
+type image struct {
+	goDocker.APIImages
+	host string
+}
+
+type imageSlice []image
+
+// imagesMsg carries every image of one host
+type imagesMsg struct {
+	Host   string
+	Images imageSlice
+}
+
+func newImageSlice(host string, images []goDocker.APIImages) imageSlice {
+	is := make(imageSlice, len(images))
+	for i, img := range images {
+		is[i] = image{img, host}
+	}
+	return is
+}
+
+// mergeImages combines the images of every host
+func mergeImages(byHost map[string]imageSlice) (merged imageSlice) {
+	for _, images := range byHost {
+		merged = append(merged, images...)
+	}
+	return
+}
+
+// hasHosts tells if the images come from several hosts, so need a host column
+func (is imageSlice) hasHosts() bool {
+	return len(is) > 0 && len(is[0].host) > 0
+}
+
+func (is imageSlice) headers() []string {
+	if is.hasHosts() {
+		return append(append([]string{}, imageHeaders...), "Host")
+	}
+	return imageHeaders
+}
+
+func (is imageSlice) sort() {
+	sort.Slice(is, func(i int, j int) bool {
+		if is[i].Created == is[j].Created {
+			return is[i].host < is[j].host
+		}
+		return is[i].Created > is[j].Created
+	})
+}
+
+// containersByImage counts the containers using each image id, per host
+func (cm containerMap) containersByImage() map[string]int {
+	counts := make(map[string]int)
+	for _, cont := range cm {
+		counts[cont.host+"/"+cont.Image]++
+	}
+	return counts
+}
+
+// rows gives one row per repo tag, like `docker images`
+func (is imageSlice) rows(offset int, containers containerMap) (rows [][]string) {
+	var (
+		counts = containers.containersByImage()
+		index  = 0
+	)
+
+	is.sort()
+
+	for _, img := range is {
+		tags := img.RepoTags
+		if len(tags) == 0 {
+			tags = []string{"<none>:<none>"}
+		}
+		for _, tag := range tags {
+			if index >= offset {
+				row := []string{
+					tag,
+					shortImageID(img.ID),
+					units.HumanSize(float64(img.Size)),
+					units.HumanDuration(time.Since(time.Unix(img.Created, 0))) + " ago",
+					strconv.Itoa(counts[img.host+"/"+img.ID]),
+				}
+				if is.hasHosts() {
+					row = append(row, img.host)
+				}
+				rows = append(rows, row)
+			}
+			index++
+		}
+	}
+	return
+}
+
+func (is imageSlice) numRows() (num int) {
+	for _, img := range is {
+		if len(img.RepoTags) == 0 {
+			num++
+		}
+		num += len(img.RepoTags)
+	}
+	return
+}
+
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// imagesView is the model's subscriber for the image screen
+type imagesView struct {
+	m      *model
+	view   *view
+	offset int
+}
+
+func newImagesView(m *model, v *view) *imagesView {
+	return &imagesView{m: m, view: v}
+}
+
+func (iv *imagesView) handle(e event) {
+	switch e := e.(type) {
+	case imagesUpdated:
+		Info.Println("Got images event")
+		if iv.offset >= iv.m.images.numRows() {
+			iv.offset = 0
+		}
+		iv.render()
+	case containerUpdated, containerRemoved:
+		if iv.m.screen == ImageScreen {
+			// container counts per image may have changed
+			iv.render()
+		}
+	case screenChanged:
+		if e.Screen == ImageScreen {
+			iv.render()
+		}
+	case keyPressed:
+		if iv.m.screen != ImageScreen {
+			return
+		}
+		switch e.Input.Event {
+		case KeyArrowDown:
+			if iv.offset < iv.m.images.numRows()-1 {
+				iv.offset++
+			}
+		case KeyArrowUp:
+			if iv.offset > 0 {
+				iv.offset--
+			}
+		}
+		iv.render()
+	}
+}
+
+func (iv *imagesView) render() {
+	iv.view.RenderImages(iv.m.images, iv.m.containers, iv.offset)
+}
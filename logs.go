@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	. "github.com/byrnedo/dockdash/logger"
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+// lines kept in the scroll back buffer
+const maxLogLines = 5000
+
+type logStream struct {
+	cncl context.CancelFunc
+}
+
+type logLine struct {
+	ContainerID string
+	Stderr      bool
+	Text        string
+}
+
+// logLineWriter splits a log stream into lines and sends them on
+type logLineWriter struct {
+	ctx         context.Context
+	containerID string
+	stderr      bool
+	events      chan<- event
+	buf         []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		select {
+		case <-w.ctx.Done():
+			return 0, w.ctx.Err()
+		case w.events <- logReceived{logLine{w.containerID, w.stderr, line}}:
+		}
+	}
+	return len(p), nil
+}
+
+// StartLogs follows the logs of the container, sending each line on events until StopLogs is called
+// or the container dies.
+func (sl *StatsListener) StartLogs(cont container, tail int, events chan<- event) {
+	sl.StopLogs(cont.ID)
+
+	ctx, cncl := context.WithCancel(sl.ctx)
+	stream := &logStream{cncl}
+	sl.logsMutex.Lock()
+	if sl.logStreams == nil {
+		sl.logStreams = make(map[string]*logStream)
+	}
+	sl.logStreams[cont.ID] = stream
+	sl.logsMutex.Unlock()
+
+	go func() {
+		// docker ends the stream by itself when the container stops
+		defer sl.removeLogStream(cont.ID, stream)
+
+		Info.Println("Following logs for", cont.ID)
+		err := sl.DockerClient.Logs(goDocker.LogsOptions{
+			Context:      ctx,
+			Container:    cont.ID,
+			OutputStream: &logLineWriter{ctx: ctx, containerID: cont.ID, events: events},
+			ErrorStream:  &logLineWriter{ctx: ctx, containerID: cont.ID, stderr: true, events: events},
+			Tail:         strconv.Itoa(tail),
+			Follow:       true,
+			Stdout:       true,
+			Stderr:       true,
+			RawTerminal:  cont.Config != nil && cont.Config.Tty,
+		})
+		if err != nil && ctx.Err() == nil {
+			Error.Println("Failed to follow logs for", cont.ID, ":", err)
+			select {
+			case <-ctx.Done():
+			case events <- logReceived{logLine{cont.ID, true, "Failed to follow logs: " + err.Error()}}:
+			}
+		}
+		Info.Println("Stopped following logs for", cont.ID)
+	}()
+}
+
+// StopLogs tears down the log stream for the container, if there is one
+func (sl *StatsListener) StopLogs(id string) {
+	sl.logsMutex.Lock()
+	stream := sl.logStreams[id]
+	sl.logsMutex.Unlock()
+	if stream != nil {
+		sl.removeLogStream(id, stream)
+	}
+}
+
+func (sl *StatsListener) removeLogStream(id string, stream *logStream) {
+	sl.logsMutex.Lock()
+	defer sl.logsMutex.Unlock()
+	stream.cncl()
+	if sl.logStreams[id] == stream {
+		delete(sl.logStreams, id)
+	}
+}
+
+// logsView is the model's subscriber for the log screen. It follows the logs of the selected container while the
+// screen is shown, with the lines coming back on events.
+type logsView struct {
+	m         *model
+	view      *view
+	listeners listenerSet
+	events    chan<- event
+	tail      int
+
+	containerID string
+	host        string
+	name        string
+	lines       []logLine
+	scrollBack  int
+	paused      bool
+}
+
+func newLogsView(m *model, v *view, listeners listenerSet, events chan<- event, tail int) *logsView {
+	return &logsView{m: m, view: v, listeners: listeners, events: events, tail: tail}
+}
+
+func (lv *logsView) handle(e event) {
+	switch e := e.(type) {
+	case screenChanged:
+		if e.Screen == LogScreen {
+			lv.follow()
+		} else {
+			lv.stop()
+		}
+	case containerUpdated, containerRemoved:
+		if lv.m.screen == LogScreen {
+			// the selected container may have changed
+			lv.follow()
+		}
+	case logReceived:
+		if e.Line.ContainerID != lv.containerID {
+			// from a stream that has just been stopped
+			return
+		}
+		lv.lines = append(lv.lines, e.Line)
+		if len(lv.lines) > maxLogLines {
+			lv.lines = lv.lines[len(lv.lines)-maxLogLines:]
+		}
+		if lv.paused || lv.scrollBack > 0 {
+			// keep the same lines in view
+			lv.scroll(1)
+		} else {
+			lv.render()
+		}
+	case keyPressed:
+		if lv.m.screen != LogScreen {
+			return
+		}
+		switch e.Input.Event {
+		case KeyL:
+			lv.m.SetScreen(ContainerScreen)
+		case KeyArrowUp:
+			lv.scroll(1)
+		case KeyArrowDown:
+			lv.scroll(-1)
+		case KeyPageUp:
+			lv.scroll(lv.view.LogList.Inner.Dy())
+		case KeyPageDown:
+			lv.scroll(-lv.view.LogList.Inner.Dy())
+		case KeySpace:
+			lv.paused = !lv.paused
+			if !lv.paused {
+				lv.scrollBack = 0
+			}
+			lv.render()
+		}
+	}
+}
+
+func (lv *logsView) render() {
+	lv.view.RenderLogs(lv.name, lv.lines, lv.scrollBack, lv.paused)
+}
+
+func (lv *logsView) stop() {
+	if len(lv.containerID) > 0 {
+		lv.listeners.forHost(lv.host).StopLogs(lv.containerID)
+		lv.containerID = ""
+	}
+}
+
+// follow switches the log pane to the container at the top of the list
+func (lv *logsView) follow() {
+	cont, ok := lv.m.Selected()
+	if ok && cont.ID == lv.containerID {
+		return
+	}
+	lv.stop()
+	lv.lines = nil
+	lv.scrollBack = 0
+	lv.paused = false
+	lv.name = ""
+	if ok {
+		lv.containerID = cont.ID
+		lv.host = cont.host
+		lv.name = cont.shortName()
+		lv.listeners.forHost(cont.host).StartLogs(cont, lv.tail, lv.events)
+	}
+	lv.render()
+}
+
+func (lv *logsView) scroll(lines int) {
+	lv.scrollBack += lines
+	if lv.scrollBack > len(lv.lines)-1 {
+		lv.scrollBack = len(lv.lines) - 1
+	}
+	if lv.scrollBack < 0 {
+		lv.scrollBack = 0
+	}
+	lv.render()
+}
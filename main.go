@@ -4,33 +4,66 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"sync"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	. "github.com/byrnedo/dockdash/logger"
-	goDocker "github.com/fsouza/go-dockerclient"
 	ui "github.com/gizak/termui/v3"
 	flag "github.com/ogier/pflag"
 )
 
-var (
-	newContainerChan    chan goDocker.Container
-	removeContainerChan chan string
-	uiEventChan         chan uiEvent
-	drawStatsChan       chan StatsMsg
-)
-
+var configFlag = flag.String("config", "", "Path to the config file, defaults to $XDG_CONFIG_HOME/dockdash/config.yaml")
 var logFileFlag = flag.String("log-file", "", "Path to log file")
-var dockerEndpoint = flag.String("docker-endpoint", "", "Docker connection endpoint")
+var dockerEndpointFlag stringsFlag
+var tlsCertFlag = flag.String("tls-cert", "", "Path to the TLS client certificate")
+var tlsKeyFlag = flag.String("tls-key", "", "Path to the TLS client key")
+var tlsCAFlag = flag.String("tls-ca", "", "Path to the CA certificate to verify the daemon with")
+var tlsVerifyFlag = flag.Bool("tls-verify", false, "Use TLS and verify the daemon's certificate against --tls-ca")
+var logTailFlag = flag.Int("log-tail", 100, "Number of log lines to show from before following a container's logs")
+var onceFlag = flag.Bool("once", false, "Print a single round of stats to stdout instead of starting the dashboard")
+var roundsFlag = flag.Int("rounds", 0, "Print this many rounds of stats to stdout instead of starting the dashboard")
+var outputFlag = flag.StringP("output", "o", "table", "Output format for --once and --rounds: json, csv or table")
+var screenshotFlag = flag.Bool("screenshot", false, "Print the dashboard as plain text once every running container has stats, instead of starting it")
+var screenshotSizeFlag = flag.String("screenshot-size", "120x40", "Screen size of --screenshot, as WIDTHxHEIGHT")
+var recordFlag = flag.String("record", "", "Record what the dashboard shows to this file, to watch again with --replay")
+var replayFlag = flag.String("replay", "", "Replay a file written by --record instead of connecting to docker")
+var metricsListenFlag = flag.String("metrics-listen", "", "Serve prometheus metrics on this address, e.g. :9323")
+var sortFlag = flag.String("sort", "uptime", "Initial sort order: uptime, cpu, mem, name or image")
+var infoFlag = flag.String("info", "image", "Initial info column: image, names, ports, mounts, command, entrypoint, envs, volumes, created, status, net or io")
+var refreshFlag = flag.Duration("refresh", time.Second, "How often the dashboard is redrawn")
+var filterFlag stringsFlag
+var columnsFlag = flag.String("columns", "", "Start in the table view with these columns, comma separated: "+strings.Join(columnNames(), ", "))
+var themeFlag = flag.String("theme", "", "Color theme: dark, light or mono. Defaults to mono when NO_COLOR is set, dark otherwise")
+var cpuThresholdFlag = flag.String("cpu-threshold", alertThresholds.CPU.String(), "CPU percent at which a container is shown as a warning and as critical")
+var memThresholdFlag = flag.String("mem-threshold", alertThresholds.Mem.String(), "Memory percent of the limit at which a container is shown as a warning and as critical")
+var groupFlag = flag.BoolP("group", "g", false, "Group containers by docker compose project")
+var allFlag = flag.BoolP("all", "a", false, "Show stopped containers too")
 var helpFlag = flag.Bool("help", false, "help")
 var versionFlag = flag.Bool("version", false, "print version")
 
+var initialSortKey sortKey
+var initialFilters containerFilters
+var dockerEndpoints []endpointConfig
+var dockerHosts []dockerHost
+var initialColumns []column
+var initialInfoType dockerInfoType
+var screenshotWidth, screenshotHeight int
+var initialTableMode bool
+
 func init() {
+	flag.Var(&dockerEndpointFlag, "docker-endpoint", "Docker connection endpoint, as [name=]endpoint, ssh://[user@]host is tunnelled over ssh. Repeat to monitor several hosts")
+	flag.VarP(&filterFlag, "filter", "f", "Only show containers matching a filter, name=, label=, ancestor= or status=. Can be repeated")
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: dockdash [options]\n\n")
 		flag.PrintDefaults()
 	}
+}
 
+// parseFlags reads the command line and config file, exiting on bad input. It is kept out of init so the
+// tests can run without dockdash's flags.
+func parseFlags() {
 	flag.Parse()
 
 	if *helpFlag {
@@ -41,9 +74,132 @@ func init() {
 		fmt.Println(VERSION)
 		os.Exit(0)
 	}
+
+	if err := applyConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var err error
+	if *refreshFlag <= 0 {
+		fmt.Fprintln(os.Stderr, "--refresh has to be more than 0")
+		os.Exit(1)
+	}
+	if initialSortKey, err = parseSortKey(*sortFlag); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if initialInfoType, err = parseInfoType(*infoFlag); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, expr := range filterFlag {
+		filter, err := parseFilter(expr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		initialFilters = append(initialFilters, filter)
+	}
+	tls := tlsOptions{TLSCert: *tlsCertFlag, TLSKey: *tlsKeyFlag, TLSCA: *tlsCAFlag, TLSVerify: *tlsVerifyFlag}
+	if dockerHosts, err = parseHosts(dockerEndpoints, tls); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	columns := *columnsFlag
+	if len(columns) == 0 {
+		columns = defaultColumns
+		if len(dockerHosts) > 1 {
+			columns = "host," + columns
+		}
+	}
+	if initialColumns, err = parseColumns(columns); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if screenshotWidth, screenshotHeight, err = parseScreenSize(*screenshotSizeFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "--screenshot-size:", err)
+		os.Exit(1)
+	}
+	if len(*replayFlag) > 0 && (len(*recordFlag) > 0 || *onceFlag || *roundsFlag > 0 || *screenshotFlag) {
+		fmt.Fprintln(os.Stderr, "--replay can't be combined with --record, --once, --rounds or --screenshot")
+		os.Exit(1)
+	}
+}
+
+// applyConfig loads the config file, using its settings for the flags that weren't given
+func applyConfig() error {
+	path := *configFlag
+	if len(path) == 0 {
+		path = defaultConfigPath()
+	}
+	cfg, err := loadConfig(path, len(*configFlag) > 0)
+	if err != nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	initialTableMode = given["columns"]
+
+	if given["docker-endpoint"] {
+		for _, expr := range dockerEndpointFlag {
+			dockerEndpoints = append(dockerEndpoints, endpointConfig{Endpoint: expr})
+		}
+	} else {
+		dockerEndpoints = cfg.Endpoints
+	}
+	if !given["refresh"] && cfg.Refresh > 0 {
+		*refreshFlag = cfg.Refresh
+	}
+	if !given["sort"] && len(cfg.Sort) > 0 {
+		*sortFlag = cfg.Sort
+	}
+	if !given["info"] && len(cfg.Info) > 0 {
+		*infoFlag = cfg.Info
+	}
+	if !given["columns"] && len(cfg.Columns) > 0 {
+		*columnsFlag = strings.Join(cfg.Columns, ",")
+	}
+	if !given["theme"] {
+		*themeFlag = cfg.Theme
+	}
+	if len(*themeFlag) == 0 {
+		*themeFlag = defaultThemeName()
+	}
+	theme, err := findTheme(*themeFlag)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Colors.merge(theme).apply(); err != nil {
+		return err
+	}
+
+	alertThresholds = cfg.Thresholds
+	if given["cpu-threshold"] {
+		if alertThresholds.CPU, err = parseThreshold(*cpuThresholdFlag); err != nil {
+			return fmt.Errorf("--cpu-threshold: %w", err)
+		}
+	}
+	if given["mem-threshold"] {
+		if alertThresholds.Mem, err = parseThreshold(*memThresholdFlag); err != nil {
+			return fmt.Errorf("--mem-threshold: %w", err)
+		}
+	}
+	if err := alertThresholds.CPU.validate(); err != nil {
+		return fmt.Errorf("cpu threshold: %w", err)
+	}
+	if err := alertThresholds.Mem.validate(); err != nil {
+		return fmt.Errorf("mem threshold: %w", err)
+	}
+
+	return remapKeys(keyMap, cfg.Keys)
 }
 
 func main() {
+	parseFlags()
 
 	if len(*logFileFlag) > 0 {
 		file, err := os.OpenFile(*logFileFlag, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
@@ -56,164 +212,580 @@ func main() {
 	}
 
 	var (
-		docker *goDocker.Client
-		err    error
+		listeners listenerSet
+		player    *replayer
+		rec       *recorder
 	)
-
-	if len(*dockerEndpoint) > 0 {
-		docker, err = goDocker.NewClient(*dockerEndpoint)
+	if len(*replayFlag) > 0 {
+		recording, err := loadRecording(*replayFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load the recording:", err)
+			os.Exit(1)
+		}
+		player = newReplayer(recording)
+		listeners = player.listeners()
 	} else {
-		docker, err = goDocker.NewClientFromEnv()
+		// connection errors are returned by Open, and shown in the ui. Hosts with a client are retried.
+		for _, host := range dockerHosts {
+			docker, tunnel, err := host.connect()
+			sl := &StatsListener{Host: host.Name, tunnel: tunnel, connectErr: err}
+			if docker != nil {
+				sl.DockerClient = docker
+			}
+			listeners = append(listeners, sl)
+		}
 	}
 
-	if err != nil {
-		panic(err)
+	if *allFlag || initialFilters.hasStatus() {
+		listeners.ListStopped()
 	}
 
-	err = ui.Init()
-	if err != nil {
-		panic(err)
+	if *onceFlag || *roundsFlag > 0 {
+		rounds := *roundsFlag
+		if rounds < 1 {
+			rounds = 1
+		}
+		if err := runSnapshot(listeners, os.Stdout, snapshotOptions{
+			format:  *outputFlag,
+			rounds:  rounds,
+			showAll: *allFlag,
+			order:   sortOrder{key: initialSortKey},
+			filters: initialFilters,
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	defer ui.Close()
+	if *screenshotFlag {
+		if err := runScreenshot(listeners, os.Stdout, screenshotOptions{
+			width:     screenshotWidth,
+			height:    screenshotHeight,
+			showAll:   *allFlag,
+			grouped:   *groupFlag,
+			tableMode: initialTableMode,
+			columns:   initialColumns,
+			infoType:  initialInfoType,
+			order:     sortOrder{key: initialSortKey},
+			filters:   initialFilters,
+		}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	var uiView = NewView()
+	var (
+		events = make(chan event)
+		m      = newModel()
+	)
 
-	uiView.SetLayout()
+	if len(*metricsListenFlag) > 0 {
+		metrics := newMetricsExporter()
+		if err := metrics.Listen(*metricsListenFlag); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to serve metrics:", err)
+			os.Exit(1)
+		}
+		m.Subscribe(metrics.handle)
+	}
 
-	newContainerChan = make(chan goDocker.Container)
-	removeContainerChan = make(chan string)
-	drawStatsChan = make(chan StatsMsg)
-	uiEventChan = make(chan uiEvent)
+	if len(*recordFlag) > 0 {
+		var err error
+		if rec, err = createRecording(*recordFlag, listeners.hostNames()); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to start recording:", err)
+			os.Exit(1)
+		}
+		m.Subscribe(rec.handle)
+	}
 
-	// Statistics
+	if err := ui.Init(); err != nil {
+		panic(err)
+	}
+
+	defer ui.Close()
 
-	sl := &StatsListener{DockerClient: docker}
+	var uiView = NewView(terminalRenderer{})
+
+	uiView.SetColumns(initialColumns)
+	uiView.SetTableMode(initialTableMode)
+	uiView.SetLayout()
+	uiView.SetHosts(listeners.hostNames())
+	if rec != nil {
+		uiView.SetSession("Recording to " + *recordFlag)
+	}
 
 	//setup initial containers
 	uiView.Render()
 
-	go handleUiEvents()
-	Info.Println("ui event loop running")
+	m.Subscribe(dashboard(m, uiView, listeners, events, rec, player))
+	// after the dashboard, which picks the selected container
+	m.Subscribe(newLogsView(m, uiView, listeners, events, *logTailFlag).handle)
+	m.Subscribe(newImagesView(m, uiView).handle)
+	m.Subscribe(newEventLogView(m, uiView).handle)
 
-	wg := sync.WaitGroup{}
+	go handleUiEvents(events)
+	Info.Println("ui event loop running")
 
-	go func() {
-		wg.Add(1)
-		mainLoop(uiView, sl)
-		wg.Done()
-	}()
+	go publishTicks(events, *refreshFlag)
 
-	Info.Println("opening stats listener")
-	sl.Open(newContainerChan, removeContainerChan, drawStatsChan)
-	Info.Println("stats listener open")
-	wg.Wait()
+	if player != nil {
+		Info.Println("replaying", *replayFlag)
+		player.Open(events)
+	} else {
+		go func() {
+			Info.Println("opening stats listeners")
+			// hosts that can't be reached send their status, and are retried in the background
+			if err := listeners.Open(events); err != nil {
+				Error.Println("Failed to open stats listeners:", err)
+			}
+			Info.Println("stats listeners open")
+		}()
+	}
 
+	// the dashboard exits the process when it is quit
+	m.Run(events)
 }
 
-func mainLoop(uiView *view, sl *StatsListener) {
+// dashboard returns the model's subscriber that draws the container screen and the info bar, acts on the keys
+// pressed there and the global ones, and switches screens. The log, image and event log screens are subscribers of
+// their own. Action results and exited shells come back on events. It stops rec if the recording fails, and plays
+// back player instead of talking to docker, when they aren't nil.
+func dashboard(m *model, uiView *view, listeners listenerSet, events chan<- event, rec *recorder, player *replayer) func(e event) {
 
 	var (
-		inspectMode       = false
-		horizPosition     = 0
-		offset            = 0
-		maxOffset         = 0
-		currentStats      *StatsMsg
-		currentContainers = make(containerMap)
-		ticker            = time.NewTicker(1 * time.Second)
+		inspectMode   = false
+		horizPosition = int(initialInfoType)
+		statsChanged  = false
+		confirming    *pendingAction
+		showAll       = *allFlag
+		historyWindow = 0
+		order         = sortOrder{key: initialSortKey}
+		searching     = false
+		searchText    = ""
+		searchFilters containerFilters
+		grouped       = *groupFlag
+		tableMode     = initialTableMode
+		collapsed     = make(map[string]bool)
+		// set while a shell in a container has the terminal
+		execing = false
 	)
-	for {
-		select {
-		case e := <-uiEventChan:
+
+	// visibleContainers narrows the containers down to the ones that pass the --filter flags and search
+	visibleContainers := func() containerMap {
+		filters := append(append(containerFilters{}, initialFilters...), searchFilters...)
+		return filters.apply(m.containers.visible(showAll || filters.hasStatus()))
+	}
+
+	// layoutRows lays the sorted containers out as the list shows them, under project headers when grouped
+	layoutRows := func(stats map[string]ContainerStats) listRows {
+		sorted := visibleContainers().sorted(order, stats)
+		if grouped {
+			return groupedRows(sorted, collapsed)
+		}
+		return flatRows(sorted)
+	}
+
+	currentRows := func() listRows {
+		return layoutRows(m.stats.byID())
+	}
+
+	updateHistory := func(rows listRows) {
+		var (
+			window   = historyWindows[historyWindow]
+			cont, ok = rows.selected(m.selected)
+		)
+		if !ok {
+			uiView.UpdateHistory("", nil, window)
+			return
+		}
+		history := listeners.forHost(cont.host).History
+		if player != nil {
+			history = player.History
+		}
+		uiView.UpdateHistory(cont.shortName(), history(cont.ID, window), window)
+	}
+
+	// unavailable tells, in the info bar, that there is no daemon to ask while replaying
+	unavailable := func() bool {
+		if player == nil {
+			return false
+		}
+		uiView.SetStatus("Not available in a replay")
+		uiView.UpdateInfoBar(m.containers, m.stats)
+		return true
+	}
+
+	renderContainers := func() {
+		var (
+			stats = m.stats.byID()
+			rows  = layoutRows(stats)
+		)
+		m.clampSelection(len(rows))
+		m.selectedID = ""
+		if cont, ok := rows.selected(m.selected); ok {
+			m.selectedID = cont.ID
+		}
+		updateHistory(rows)
+		uiView.RenderContainers(rows, stats, dockerInfoType(horizPosition), m.selected, inspectMode, order)
+	}
+
+	requestAction := func(action containerAction) {
+		if unavailable() {
+			return
+		}
+		row, ok := currentRows().atOffset(m.selected)
+		if !ok {
+			return
+		}
+		var p pendingAction
+		if row.header() {
+			p = projectAction(action, row.project)
+		} else {
+			p = actionOn(action, row.cont)
+		}
+		switch {
+		case len(p.conts) == 0:
+			uiView.SetStatus("Nothing to " + strings.ToLower(actionNames[action]) + " in " + p.name)
+		case action.destructive():
+			confirming = &p
+			uiView.SetStatus(p.prompt())
+		default:
+			uiView.SetStatus(actionNames[action] + " " + p.name + "...")
+			runAction(listeners, p, events)
+		}
+		uiView.UpdateInfoBar(m.containers, m.stats)
+	}
+
+	return func(ev event) {
+		switch ev := ev.(type) {
+		case keyPressed:
+			in := ev.Input
+			e := in.Event
+			if execing {
+				// the shell has the terminal
+				return
+			}
+			if confirming != nil && e != Resize {
+				if e == KeyY {
+					uiView.SetStatus(actionNames[confirming.action] + " " + confirming.name + "...")
+					runAction(listeners, *confirming, events)
+				} else {
+					uiView.SetStatus("")
+				}
+				confirming = nil
+				uiView.UpdateInfoBar(m.containers, m.stats)
+				return
+			}
+			if searching && e != Resize {
+				switch in.ID {
+				case "<Enter>":
+					searching = false
+				case "<Escape>":
+					searching = false
+					searchText = ""
+				case "<Backspace>", "<C-<Backspace>>":
+					if runes := []rune(searchText); len(runes) > 0 {
+						searchText = string(runes[:len(runes)-1])
+					}
+				case "<Space>":
+					searchText += " "
+				default:
+					if utf8.RuneCountInString(in.ID) == 1 {
+						searchText += in.ID
+					}
+				}
+
+				status := ""
+				if searching {
+					status = "/" + searchText
+				}
+				if filters, err := parseFilters(searchText); err != nil {
+					status += "  " + err.Error()
+				} else {
+					searchFilters = filters
+					if filters.hasStatus() {
+						listeners.ListStopped()
+					}
+				}
+				uiView.SetStatus(status)
+				m.Select(0)
+				uiView.SetFilter(searchText)
+				renderContainers()
+				uiView.UpdateInfoBar(m.containers, m.stats)
+				return
+			}
+
 			switch e {
 			case Resize:
 				uiView.ResetSize()
+				return
 			case KeyQ, KeyCtrlC, KeyCtrlD:
-				sl.Close()
+				listeners.Close()
+				if err := rec.Close(); err != nil {
+					Error.Println("Failed to finish the recording:", err)
+				}
 				ui.Close()
 				os.Exit(0)
+			case KeyTab:
+				if unavailable() {
+					return
+				}
+				if m.screen == ContainerScreen {
+					m.SetScreen(ImageScreen)
+				} else {
+					m.SetScreen(ContainerScreen)
+				}
+				return
+			}
+
+			if m.screen != ContainerScreen {
+				// the keys of the other screens are handled by their views
+				return
+			}
+
+			if player != nil && player.handleKey(e) {
+				uiView.SetSession(player.String())
+				uiView.UpdateInfoBar(m.containers, m.stats)
+				return
+			}
+
+			switch e {
 			case KeyArrowLeft:
+				if tableMode {
+					break
+				}
 				if horizPosition > 0 {
 					horizPosition--
 				}
-				uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
+				renderContainers()
 			case KeyArrowRight:
+				if tableMode {
+					break
+				}
 				if horizPosition < maxHorizPos {
 					horizPosition++
 				}
-				uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
+				renderContainers()
 			case KeyArrowDown:
-				if offset < maxOffset && offset < maxContainers {
-					offset++
-				}
-				uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
+				m.Select(m.selected + 1)
+				renderContainers()
 				//shift the list down
 			case KeyArrowUp:
-				if offset > 0 {
-					offset--
-				}
-				uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
+				m.Select(m.selected - 1)
+				renderContainers()
 				//shift the list up
 			case KeyI:
 				inspectMode = !inspectMode
-				uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
+				if tableMode {
+					// inspecting needs the info list, so go back to it
+					tableMode = false
+					inspectMode = true
+					uiView.SetTableMode(tableMode)
+					uiView.SetLayout()
+					ui.Clear()
+				}
+				renderContainers()
+			case KeyV:
+				tableMode = !tableMode
+				inspectMode = false
+				uiView.SetTableMode(tableMode)
+				uiView.SetLayout()
+				ui.Clear()
+				renderContainers()
+			case KeyA:
+				showAll = !showAll
+				if showAll {
+					listeners.ListStopped()
+				}
+				renderContainers()
+			case KeyW:
+				historyWindow = (historyWindow + 1) % len(historyWindows)
+				renderContainers()
+			case KeySlash:
+				searching = true
+				uiView.SetStatus("/" + searchText)
+				uiView.UpdateInfoBar(m.containers, m.stats)
+			case KeyO:
+				order = order.next()
+				renderContainers()
+			case KeyShiftO:
+				order.reverse = !order.reverse
+				renderContainers()
+			case KeyG:
+				grouped = !grouped
+				m.Select(0)
+				renderContainers()
+			case KeyC:
+				// collapse or expand the project of the selected row, keeping its header selected
+				row, ok := currentRows().atOffset(m.selected)
+				if !ok || !grouped {
+					break
+				}
+				// a header row has no container
+				var key string
+				if row.header() {
+					key = row.project.key()
+				} else {
+					key = projectKey(row.cont.host, row.cont.composeProject())
+				}
+				collapsed[key] = !collapsed[key]
+				m.Select(currentRows().headerIndex(key))
+				renderContainers()
+			case KeyL:
+				if unavailable() {
+					break
+				}
+				m.SetScreen(LogScreen)
+			case KeyShiftE:
+				m.SetScreen(EventScreen)
+			case KeyE:
+				cont, ok := currentRows().selected(m.selected)
+				if !ok || !cont.running() || unavailable() {
+					break
+				}
+				// the shell runs in its own routine, so the model keeps up with docker in the meantime
+				execing = true
+				uiView.Suspend(true)
+				ui.Close()
+				fmt.Printf("Starting a shell in %s, exit it to get back to dockdash\n", cont.shortName())
+				go func(host string, id string, name string) {
+					err := listeners.forHost(host).ExecShell(id)
+					events <- execFinished{name, err}
+				}(cont.host, cont.ID, cont.shortName())
+			case KeyS:
+				requestAction(StopAction)
+			case KeyT:
+				requestAction(StartAction)
+			case KeyR:
+				requestAction(RestartAction)
+			case KeyK:
+				requestAction(KillAction)
+			case KeyP:
+				requestAction(PauseAction)
+			case KeyU:
+				requestAction(UnpauseAction)
 			default:
 				Info.Printf("Got unhandled key %+v\n", e)
 			}
-		case cont := <-newContainerChan:
+		case containerUpdated:
 			Info.Println("Got new containers event")
-			currentContainers[cont.ID] = container{&cont}
-			maxOffset = len(currentContainers) - 1
-			uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
-
-		case removedContainerID := <-removeContainerChan:
-			maxOffset = len(currentContainers) - 1
-			if offset >= maxOffset {
-				offset = maxOffset
+			cont, previous := ev.Container, ev.Previous
+			if previous != nil && previous.health() != healthUnhealthy && cont.health() == healthUnhealthy {
+				uiView.Flash(cont.shortName() + " is unhealthy")
+				uiView.UpdateInfoBar(m.containers, m.stats)
+			}
+			if previous != nil && !previous.State.OOMKilled && cont.State.OOMKilled {
+				uiView.Flash(cont.shortName() + " was killed for running out of memory")
+				uiView.UpdateInfoBar(m.containers, m.stats)
 			}
-			Info.Printf("%d, %d, %d", offset, maxOffset, horizPosition)
-			Info.Println("Got dead container event")
-			delete(currentContainers, removedContainerID)
+			renderContainers()
 
-			uiView.RenderContainers(currentContainers, dockerInfoType(horizPosition), offset, inspectMode)
+		case containerRemoved:
+			Info.Println("Got removed container event")
+			renderContainers()
 
-		case newStatsCharts := <-drawStatsChan:
+		case statsUpdated:
+			// drawn on the next tick
+			statsChanged = true
 
-			currentStats = &newStatsCharts
-			uiView.UpdateStats(currentStats, offset)
+		case screenChanged:
+			uiView.SetScreen(ev.Screen)
 
-		case <-ticker.C:
-			uiView.UpdateInfoBar(currentContainers, currentStats)
+		case execFinished:
+			if err := ui.Init(); err != nil {
+				panic(err)
+			}
+			execing = false
+			uiView.Suspend(false)
+			uiView.SetLayout()
+			if ev.Err != nil {
+				uiView.SetStatus("Exec into " + ev.Name + " failed: " + ev.Err.Error())
+			}
+			uiView.SetScreen(m.screen)
+			renderContainers()
+			uiView.UpdateInfoBar(m.containers, m.stats)
+
+		case actionFinished:
+			uiView.SetStatus(ev.Result)
+			uiView.UpdateInfoBar(m.containers, m.stats)
+
+		case connChanged:
+			uiView.SetConnStatus(ev.Status)
+			uiView.UpdateInfoBar(m.containers, m.stats)
+
+		case tick:
+			// the recorder has written this tick's stats already, it is subscribed before the dashboard
+			if err := rec.Err(); err != nil {
+				uiView.SetStatus("Recording stopped: " + err.Error())
+				uiView.SetSession("")
+				rec.Close()
+				rec = nil
+			}
+			if player != nil {
+				uiView.SetSession(player.String())
+			}
+			if statsChanged {
+				statsChanged = false
+				renderContainers()
+			}
+			uiView.UpdateInfoBar(m.containers, m.stats)
 		}
 	}
 }
 
-func handleUiEvents() {
+// keyMap binds termui key ids to ui events
+var keyMap = map[string]uiEvent{
+	"q":          KeyQ,
+	"<C-c>":      KeyCtrlC,
+	"<C-d>":      KeyCtrlD,
+	"<Left>":     KeyArrowLeft,
+	"<Right>":    KeyArrowRight,
+	"<Down>":     KeyArrowDown,
+	"<Up>":       KeyArrowUp,
+	"<Resize>":   Resize,
+	"i":          KeyI,
+	"s":          KeyS,
+	"t":          KeyT,
+	"r":          KeyR,
+	"k":          KeyK,
+	"p":          KeyP,
+	"u":          KeyU,
+	"y":          KeyY,
+	"n":          KeyN,
+	"<Escape>":   KeyN,
+	"a":          KeyA,
+	"<Tab>":      KeyTab,
+	"l":          KeyL,
+	"<Space>":    KeySpace,
+	"<PageUp>":   KeyPageUp,
+	"<PageDown>": KeyPageDown,
+	"w":          KeyW,
+	"o":          KeyO,
+	"O":          KeyShiftO,
+	"/":          KeySlash,
+	"e":          KeyE,
+	"g":          KeyG,
+	"c":          KeyC,
+	"v":          KeyV,
+	"E":          KeyShiftE,
+	"[":          KeyLeftBracket,
+	"]":          KeyRightBracket,
+	"-":          KeyMinus,
+	"+":          KeyPlus,
+}
+
+func handleUiEvents(events chan<- event) {
 	uiEvents := ui.PollEvents()
 	for {
 		select {
 		case e := <-uiEvents:
-			Info.Printf("%s - %s\n", e.ID, e.Type)
-			switch e.ID {
-			case "q":
-				uiEventChan <- KeyQ
-			case "<C-c>":
-				uiEventChan <- KeyCtrlC
-			case "<C-d>":
-				uiEventChan <- KeyCtrlD
-			case "<Left>":
-				uiEventChan <- KeyArrowLeft
-			case "<Right>":
-				uiEventChan <- KeyArrowRight
-			case "<Down>":
-				uiEventChan <- KeyArrowDown
-			case "<Up>":
-				uiEventChan <- KeyArrowUp
-			case "<Resize>":
-				uiEventChan <- Resize
-			case "i":
-				uiEventChan <- KeyI
+			Info.Printf("%s - %v\n", e.ID, e.Type)
+			if e.Type == ui.MouseEvent {
+				continue
 			}
+			// unbound keys are still sent on as KeyNone, for text input
+			events <- keyPressed{uiInput{keyMap[e.ID], e.ID}}
 		}
 	}
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/byrnedo/dockdash/logger"
+)
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(fakeSSHEnv); len(dir) > 0 {
+		// run by a tunnel as its ssh
+		os.Exit(fakeSSH(dir, os.Args[1:]))
+	}
+	InitLog(io.Discard, io.Discard, io.Discard, io.Discard)
+	if err := themes["dark"].apply(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestDashboardCollapsesFromAProjectHeader(t *testing.T) {
+	var (
+		m                 = newModel()
+		screen            = newBufferRenderer(160, 60)
+		v                 = NewView(screen)
+		containers, stats = testContainers()
+		press             = func(e uiEvent) { m.apply(keyPressed{uiInput{Event: e}}) }
+	)
+	v.SetLayout()
+	m.Subscribe(dashboard(m, v, listenerSet{&StatsListener{}}, make(chan event, 10), nil, nil))
+	for _, cont := range containers {
+		m.apply(containerUpdated{Container: cont})
+	}
+	m.apply(statsUpdated{stats})
+
+	// grouped, the list starts with the shop header
+	press(KeyG)
+	if !strings.Contains(screen.String(), "▾ shop") {
+		t.Fatalf("expected the shop project expanded, got:\n%s", screen.String())
+	}
+	press(KeyC)
+	if !strings.Contains(screen.String(), "▸ shop") || strings.Contains(screen.String(), "shop-web-1") {
+		t.Fatalf("expected the shop project collapsed, got:\n%s", screen.String())
+	}
+	// the header is still selected, and has no container of its own
+	press(KeyC)
+	if !strings.Contains(screen.String(), "▾ shop") {
+		t.Errorf("expected the shop project expanded again, got:\n%s", screen.String())
+	}
+}
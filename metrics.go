@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	. "github.com/byrnedo/dockdash/logger"
+)
+
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func(cs ContainerStats) float64
+}
+
+var containerMetrics = []metric{
+	{"dockdash_container_cpu_percent", "CPU usage of the container as a percentage of one core.", "gauge",
+		func(cs ContainerStats) float64 { return cs.CPUPercent }},
+	{"dockdash_container_memory_usage_bytes", "Memory used by the container.", "gauge",
+		func(cs ContainerStats) float64 { return float64(cs.MemUsage) }},
+	{"dockdash_container_memory_limit_bytes", "Memory limit of the container.", "gauge",
+		func(cs ContainerStats) float64 { return float64(cs.MemLimit) }},
+	{"dockdash_container_network_receive_bytes_total", "Bytes received over all of the container's networks.", "counter",
+		func(cs ContainerStats) float64 { return float64(cs.NetRx) }},
+	{"dockdash_container_network_transmit_bytes_total", "Bytes sent over all of the container's networks.", "counter",
+		func(cs ContainerStats) float64 { return float64(cs.NetTx) }},
+	{"dockdash_container_block_read_bytes_total", "Bytes read from block devices by the container.", "counter",
+		func(cs ContainerStats) float64 { return float64(cs.BlockRead) }},
+	{"dockdash_container_block_write_bytes_total", "Bytes written to block devices by the container.", "counter",
+		func(cs ContainerStats) float64 { return float64(cs.BlockWrite) }},
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricsExporter serves the latest container stats in the prometheus text format
+type metricsExporter struct {
+	mutex  sync.RWMutex
+	byHost map[string][]ContainerStats
+}
+
+func newMetricsExporter() *metricsExporter {
+	return &metricsExporter{byHost: make(map[string][]ContainerStats)}
+}
+
+// Update replaces the stats of one host
+func (me *metricsExporter) Update(host string, stats []ContainerStats) {
+	me.mutex.Lock()
+	me.byHost[host] = stats
+	me.mutex.Unlock()
+}
+
+// handle keeps the exporter up to date, it is subscribed to the model
+func (me *metricsExporter) handle(e event) {
+	if e, ok := e.(statsUpdated); ok {
+		me.Update(e.Stats.Host, e.Stats.Containers)
+	}
+}
+
+func (me *metricsExporter) sortedStats() (stats []ContainerStats) {
+	me.mutex.RLock()
+	for _, hostStats := range me.byHost {
+		stats = append(stats, hostStats...)
+	}
+	me.mutex.RUnlock()
+
+	sort.Slice(stats, func(i int, j int) bool {
+		if stats[i].Host != stats[j].Host {
+			return stats[i].Host < stats[j].Host
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return
+}
+
+func metricLabels(cs ContainerStats) string {
+	labels := fmt.Sprintf(`name="%s",id="%s",image="%s"`,
+		labelEscaper.Replace(cs.Name),
+		labelEscaper.Replace(cs.ID),
+		labelEscaper.Replace(cs.Image),
+	)
+	if len(cs.Host) > 0 {
+		labels += fmt.Sprintf(`,host="%s"`, labelEscaper.Replace(cs.Host))
+	}
+	return labels
+}
+
+func (me *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stats := me.sortedStats()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	fmt.Fprintln(out, "# HELP dockdash_containers_running Number of running containers.")
+	fmt.Fprintln(out, "# TYPE dockdash_containers_running gauge")
+	fmt.Fprintln(out, "dockdash_containers_running", len(stats))
+
+	for _, m := range containerMetrics {
+		fmt.Fprintf(out, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(out, "# TYPE %s %s\n", m.name, m.kind)
+		for _, cs := range stats {
+			fmt.Fprintf(out, "%s{%s} %g\n", m.name, metricLabels(cs), m.value(cs))
+		}
+	}
+}
+
+// Listen starts serving /metrics on the address, returning once the port is bound
+func (me *metricsExporter) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", me)
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			Error.Println("Metrics server stopped:", err)
+		}
+	}()
+	Info.Println("serving metrics on", listener.Addr())
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExporterServesStats(t *testing.T) {
+	me := newMetricsExporter()
+	me.handle(statsUpdated{StatsMsg{Host: "beta", Containers: []ContainerStats{
+		{Host: "beta", ID: "2", Name: `odd"name\`, Image: "busybox", CPUPercent: 12.5, MemUsage: 1024},
+	}}})
+	me.handle(statsUpdated{StatsMsg{Host: "alpha", Containers: []ContainerStats{
+		{Host: "alpha", ID: "1", Name: "web", Image: "nginx\nlatest", CPUPercent: 50, NetRx: 300},
+	}}})
+
+	server := httptest.NewServer(me)
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("expected the prometheus text format, got %q", contentType)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(body)
+
+	for _, want := range []string{
+		"# HELP dockdash_containers_running Number of running containers.\n",
+		"# TYPE dockdash_containers_running gauge\n",
+		"dockdash_containers_running 2\n",
+		"# HELP dockdash_container_cpu_percent CPU usage of the container as a percentage of one core.\n",
+		"# TYPE dockdash_container_cpu_percent gauge\n",
+		"# TYPE dockdash_container_network_receive_bytes_total counter\n",
+		// sorted by host, the newline in the image escaped
+		`dockdash_container_cpu_percent{name="web",id="1",image="nginx\nlatest",host="alpha"} 50` + "\n" +
+			`dockdash_container_cpu_percent{name="odd\"name\\",id="2",image="busybox",host="beta"} 12.5` + "\n",
+		`dockdash_container_network_receive_bytes_total{name="web",id="1",image="nginx\nlatest",host="alpha"} 300` + "\n",
+		`dockdash_container_memory_usage_bytes{name="odd\"name\\",id="2",image="busybox",host="beta"} 1024` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	// a single host has no host label
+	me = newMetricsExporter()
+	me.Update("", []ContainerStats{{ID: "1", Name: "web", Image: "nginx"}})
+	recorder := httptest.NewRecorder()
+	me.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `dockdash_container_cpu_percent{name="web",id="1",image="nginx"} 0`; !strings.Contains(recorder.Body.String(), want) {
+		t.Errorf("expected %q without a host label, got:\n%s", want, recorder.Body.String())
+	}
+}
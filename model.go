@@ -0,0 +1,207 @@
+package main
+
+import "time"
+
+// event is something the model is told about, by the stats listeners, a replay, container actions or the ui.
+// Each kind of event is its own type, below.
+type event interface {
+	isEvent()
+}
+
+// containerUpdated is sent for a new container, and whenever one changes
+type containerUpdated struct {
+	Container container
+	// the container as it was before, set by the model. Nil for a new container.
+	Previous *container
+}
+
+type containerRemoved struct {
+	ID string
+}
+
+// statsUpdated is the latest stats of one host's running containers
+type statsUpdated struct {
+	Stats StatsMsg
+}
+
+type imagesUpdated struct {
+	Images imagesMsg
+}
+
+type eventLogged struct {
+	Entry eventLogEntry
+}
+
+type connChanged struct {
+	Status connStatus
+}
+
+type actionFinished struct {
+	Result string
+}
+
+type logReceived struct {
+	Line logLine
+}
+
+type keyPressed struct {
+	Input uiInput
+}
+
+// tick is sent at the refresh interval, for the things that are redrawn or written out periodically
+type tick struct{}
+
+// screenChanged is applied when a view switches screens with SetScreen
+type screenChanged struct {
+	Screen screen
+}
+
+// execFinished is sent once a shell opened in a container exits
+type execFinished struct {
+	Name string
+	Err  error
+}
+
+func (containerUpdated) isEvent() {}
+func (containerRemoved) isEvent() {}
+func (statsUpdated) isEvent()     {}
+func (imagesUpdated) isEvent()    {}
+func (eventLogged) isEvent()      {}
+func (connChanged) isEvent()      {}
+func (actionFinished) isEvent()   {}
+func (logReceived) isEvent()      {}
+func (keyPressed) isEvent()       {}
+func (tick) isEvent()             {}
+func (screenChanged) isEvent()    {}
+func (execFinished) isEvent()     {}
+
+// model holds the containers, stats, images and event log of every host, which row of the container list is
+// selected and which screen is shown. Events are applied in the order they arrive, then passed on to the
+// subscribers, which only ever see the model from the routine running it.
+type model struct {
+	containers containerMap
+	hostStats  map[string]StatsMsg
+	// every host's stats merged, nil until some arrive
+	stats      *StatsMsg
+	hostImages map[string]imageSlice
+	images     imageSlice
+	eventLog   []eventLogEntry
+	// the row at the top of the container list, which the charts, history, logs and actions follow
+	selected int
+	// the container on that row, empty for a project header. Set by the container list as it lays out the rows.
+	selectedID  string
+	screen      screen
+	subscribers []func(e event)
+	// events raised by subscribers, applied once every subscriber has seen the current one
+	queued   []event
+	applying bool
+}
+
+func newModel() *model {
+	return &model{
+		containers: make(containerMap),
+		hostStats:  make(map[string]StatsMsg),
+		hostImages: make(map[string]imageSlice),
+	}
+}
+
+// Subscribe has fn called with every event, after the model has applied it. Subscribers are called in the order
+// they subscribed.
+func (m *model) Subscribe(fn func(e event)) {
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Run applies the events until the channel is closed
+func (m *model) Run(events <-chan event) {
+	for e := range events {
+		m.apply(e)
+	}
+}
+
+// apply updates the model with the event and passes it on to the subscribers. An event a subscriber raises while
+// being told about another, like a screen change, is applied after, so every subscriber sees the same model for
+// an event.
+func (m *model) apply(e event) {
+	m.queued = append(m.queued, e)
+	if m.applying {
+		return
+	}
+	m.applying = true
+	for len(m.queued) > 0 {
+		e := m.queued[0]
+		m.queued = m.queued[1:]
+		m.notify(m.update(e))
+	}
+	m.applying = false
+}
+
+func (m *model) update(e event) event {
+	switch e := e.(type) {
+	case containerUpdated:
+		if previous, ok := m.containers[e.Container.ID]; ok {
+			e.Previous = &previous
+		}
+		m.containers[e.Container.ID] = e.Container
+		return e
+	case containerRemoved:
+		delete(m.containers, e.ID)
+	case statsUpdated:
+		m.hostStats[e.Stats.Host] = e.Stats
+		merged := mergeStats(m.hostStats)
+		m.stats = &merged
+	case imagesUpdated:
+		m.hostImages[e.Images.Host] = e.Images.Images
+		m.images = mergeImages(m.hostImages)
+	case eventLogged:
+		m.eventLog = append(m.eventLog, e.Entry)
+		if len(m.eventLog) > maxEventLogEntries {
+			m.eventLog = m.eventLog[len(m.eventLog)-maxEventLogEntries:]
+		}
+	case screenChanged:
+		m.screen = e.Screen
+	}
+	return e
+}
+
+func (m *model) notify(e event) {
+	for _, fn := range m.subscribers {
+		fn(e)
+	}
+}
+
+// Select moves the selection to the row, which is kept on the list by clampSelection
+func (m *model) Select(row int) {
+	m.selected = row
+}
+
+// Selected returns the container on the selected row, if it is one
+func (m *model) Selected() (container, bool) {
+	cont, ok := m.containers[m.selectedID]
+	return cont, ok
+}
+
+// SetScreen switches to the screen once the event being handled has been passed to every subscriber
+func (m *model) SetScreen(s screen) {
+	m.apply(screenChanged{s})
+}
+
+// clampSelection keeps the selection within the given number of rows
+func (m *model) clampSelection(rows int) {
+	if m.selected > rows-1 {
+		m.selected = rows - 1
+	}
+	if m.selected > maxContainers {
+		m.selected = maxContainers
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+}
+
+// publishTicks sends a tick on events at every interval, for good
+func publishTicks(events chan<- event, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		events <- tick{}
+	}
+}
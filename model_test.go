@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestModelAppliesEvents(t *testing.T) {
+	var (
+		m        = newModel()
+		got      []event
+		conts, _ = testContainers()
+		web      = conts[fakeID("a")]
+	)
+	m.Subscribe(func(e event) {
+		got = append(got, e)
+	})
+
+	m.apply(containerUpdated{Container: web})
+	if e := got[0].(containerUpdated); e.Previous != nil {
+		t.Errorf("a new container has nothing before it, got %+v", e.Previous)
+	}
+	stopped := testContainer("a", "shop-web-1", "nginx:1.25", false, 0, nil)
+	m.apply(containerUpdated{Container: stopped})
+	if e := got[1].(containerUpdated); e.Previous == nil || !e.Previous.running() {
+		t.Errorf("expected the running container as it was before, got %+v", e.Previous)
+	}
+	if m.containers[web.ID].running() {
+		t.Error("expected the model to hold the stopped container")
+	}
+
+	m.apply(statsUpdated{StatsMsg{Host: "b", Containers: []ContainerStats{{ID: "2"}}}})
+	m.apply(statsUpdated{StatsMsg{Host: "a", Containers: []ContainerStats{{ID: "1"}}}})
+	if m.stats == nil || len(m.stats.Containers) != 2 || m.stats.Containers[0].ID != "1" {
+		t.Errorf("expected every host's stats merged, got %+v", m.stats)
+	}
+
+	m.apply(containerRemoved{web.ID})
+	if len(m.containers) != 0 {
+		t.Errorf("expected the container to be removed, got %d left", len(m.containers))
+	}
+	m.apply(tick{})
+	if len(got) != 6 {
+		t.Errorf("expected the subscriber to see all 6 events, got %d", len(got))
+	}
+}
+
+func TestModelCapsEventLog(t *testing.T) {
+	m := newModel()
+	for i := 0; i < maxEventLogEntries+10; i++ {
+		m.apply(eventLogged{eventLogEntry{Action: "start"}})
+	}
+	if len(m.eventLog) != maxEventLogEntries {
+		t.Errorf("expected the event log to be capped at %d, got %d", maxEventLogEntries, len(m.eventLog))
+	}
+}
+
+func TestClampSelection(t *testing.T) {
+	m := newModel()
+	m.Select(5)
+	m.clampSelection(3)
+	if m.selected != 2 {
+		t.Errorf("expected the last of 3 rows to be selected, got %d", m.selected)
+	}
+	m.clampSelection(0)
+	if m.selected != 0 {
+		t.Errorf("expected the first row to be selected in an empty list, got %d", m.selected)
+	}
+	m.Select(maxContainers + 5)
+	m.clampSelection(maxContainers + 10)
+	if m.selected != maxContainers {
+		t.Errorf("expected the selection to stop at %d, got %d", maxContainers, m.selected)
+	}
+}
+
+func TestModelAppliesScreenChangesAfterTheCurrentEvent(t *testing.T) {
+	var (
+		m   = newModel()
+		got []string
+	)
+	m.Subscribe(func(e event) {
+		if _, ok := e.(keyPressed); ok {
+			m.SetScreen(LogScreen)
+		}
+		got = append(got, fmt.Sprintf("first %T %d", e, m.screen))
+	})
+	m.Subscribe(func(e event) {
+		got = append(got, fmt.Sprintf("second %T %d", e, m.screen))
+	})
+
+	m.apply(keyPressed{uiInput{KeyL, "l"}})
+	want := []string{
+		fmt.Sprintf("first main.keyPressed %d", ContainerScreen),
+		fmt.Sprintf("second main.keyPressed %d", ContainerScreen),
+		fmt.Sprintf("first main.screenChanged %d", LogScreen),
+		fmt.Sprintf("second main.screenChanged %d", LogScreen),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected every subscriber to see the key before the screen changed, got:\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+// recordingVersion is bumped when the format changes in a way older dockdash versions can't replay
+const recordingVersion = 1
+
+// A recording is a gzipped stream of json lines: a recordingHeader, then a recordedEntry for every container
+// update, removal, stats message and event the dashboard got, in the order it got them.
+type recordingHeader struct {
+	Version int       `json:"version"`
+	Started time.Time `json:"started"`
+	Hosts   []string  `json:"hosts,omitempty"`
+}
+
+// recordedEntry holds one of a container, a removed container's id, stats or an event
+type recordedEntry struct {
+	// milliseconds since the recording started
+	At        int64               `json:"t"`
+	Host      string              `json:"h,omitempty"`
+	Container *goDocker.Container `json:"c,omitempty"`
+	Removed   string              `json:"r,omitempty"`
+	Stats     *StatsMsg           `json:"s,omitempty"`
+	Event     *eventLogEntry      `json:"e,omitempty"`
+}
+
+func (entry recordedEntry) offset() time.Duration {
+	return time.Duration(entry.At) * time.Millisecond
+}
+
+// recorder writes a recording of what the dashboard shows. A nil recorder records nothing.
+type recorder struct {
+	file    io.WriteCloser
+	zip     *gzip.Writer
+	enc     *json.Encoder
+	started time.Time
+	// the latest stats of each host since the last tick
+	pending map[string]StatsMsg
+	err     error
+}
+
+func createRecording(path string, hosts []string) (*recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := newRecorder(file, hosts, time.Now())
+	if rec.err != nil {
+		file.Close()
+		return nil, rec.err
+	}
+	return rec, nil
+}
+
+func newRecorder(file io.WriteCloser, hosts []string, started time.Time) *recorder {
+	rec := &recorder{file: file, zip: gzip.NewWriter(file), started: started, pending: make(map[string]StatsMsg)}
+	rec.enc = json.NewEncoder(rec.zip)
+	rec.write(recordingHeader{Version: recordingVersion, Started: started, Hosts: hosts})
+	return rec
+}
+
+func (rec *recorder) write(v interface{}) {
+	if rec.err != nil {
+		return
+	}
+	rec.err = rec.enc.Encode(v)
+}
+
+func (rec *recorder) record(entry recordedEntry) {
+	if rec == nil {
+		return
+	}
+	entry.At = time.Since(rec.started).Milliseconds()
+	rec.write(entry)
+}
+
+func (rec *recorder) Container(cont container) {
+	rec.record(recordedEntry{Host: cont.host, Container: recordedContainer(cont.Container)})
+}
+
+// recordedContainer copies the fields of the container the dashboard shows, leaving out the environment and the
+// rest of the config and host config, which often hold passwords and tokens.
+func recordedContainer(c *goDocker.Container) *goDocker.Container {
+	recorded := &goDocker.Container{
+		ID:              c.ID,
+		Created:         c.Created,
+		Path:            c.Path,
+		Args:            c.Args,
+		State:           c.State,
+		Image:           c.Image,
+		Node:            c.Node,
+		NetworkSettings: c.NetworkSettings,
+		Name:            c.Name,
+		Volumes:         c.Volumes,
+	}
+	if c.Config != nil {
+		recorded.Config = &goDocker.Config{Image: c.Config.Image, Entrypoint: c.Config.Entrypoint, Labels: c.Config.Labels}
+	}
+	if c.HostConfig != nil {
+		recorded.HostConfig = &goDocker.HostConfig{Binds: c.HostConfig.Binds}
+	}
+	return recorded
+}
+
+func (rec *recorder) Removed(id string) {
+	rec.record(recordedEntry{Removed: id})
+}
+
+// Stats keeps the host's stats to record on the next tick. The listeners send them for every sample of every
+// container, which would make for a recording far bigger than what the dashboard shows.
+func (rec *recorder) Stats(msg StatsMsg) {
+	if rec == nil {
+		return
+	}
+	rec.pending[msg.Host] = msg
+}
+
+// Tick records the stats kept since the last tick, and flushes so a recording cut short has everything up to now
+func (rec *recorder) Tick() {
+	if rec == nil {
+		return
+	}
+	for _, host := range sortedKeys(rec.pending) {
+		msg := rec.pending[host]
+		rec.record(recordedEntry{Host: host, Stats: &msg})
+	}
+	rec.pending = make(map[string]StatsMsg)
+	if rec.err == nil {
+		rec.err = rec.zip.Flush()
+	}
+}
+
+func (rec *recorder) Event(entry eventLogEntry) {
+	rec.record(recordedEntry{Host: entry.Host, Event: &entry})
+}
+
+// handle records the events it is subscribed to the model for
+func (rec *recorder) handle(e event) {
+	switch e := e.(type) {
+	case containerUpdated:
+		rec.Container(e.Container)
+	case containerRemoved:
+		rec.Removed(e.ID)
+	case statsUpdated:
+		rec.Stats(e.Stats)
+	case eventLogged:
+		rec.Event(e.Entry)
+	case tick:
+		rec.Tick()
+	}
+}
+
+// Err returns the error that stopped the recording, if any
+func (rec *recorder) Err() error {
+	if rec == nil {
+		return nil
+	}
+	return rec.err
+}
+
+func (rec *recorder) Close() error {
+	if rec == nil {
+		return nil
+	}
+	rec.Tick()
+	err := rec.zip.Close()
+	if closeErr := rec.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// recording is a recording read back into memory
+type recording struct {
+	recordingHeader
+	entries []recordedEntry
+}
+
+func (r *recording) length() time.Duration {
+	if len(r.entries) == 0 {
+		return 0
+	}
+	return r.entries[len(r.entries)-1].offset()
+}
+
+func loadRecording(path string) (*recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readRecording(file)
+}
+
+// readRecording reads a recording, up to the last complete entry of one that was cut short
+func readRecording(r io.Reader) (*recording, error) {
+	zip, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("not a dockdash recording: %w", err)
+	}
+	var (
+		dec = json.NewDecoder(zip)
+		rec = &recording{}
+	)
+	if err := dec.Decode(&rec.recordingHeader); err != nil {
+		return nil, fmt.Errorf("not a dockdash recording: %w", err)
+	}
+	if rec.Version != recordingVersion {
+		return nil, fmt.Errorf("recording version %d isn't supported, expected %d", rec.Version, recordingVersion)
+	}
+	for {
+		var entry recordedEntry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return rec, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading entry %d of the recording: %w", len(rec.entries)+1, err)
+		}
+		rec.entries = append(rec.entries, entry)
+	}
+}
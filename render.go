@@ -0,0 +1,86 @@
+package main
+
+import (
+	img "image"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// renderer draws the view's widgets, to the terminal or to a buffer in memory
+type renderer interface {
+	Render(items ...ui.Drawable)
+	Clear()
+	Size() (width int, height int)
+}
+
+// terminalRenderer draws with termui, which has to be initialised first
+type terminalRenderer struct{}
+
+func (terminalRenderer) Render(items ...ui.Drawable) {
+	ui.Render(items...)
+}
+
+func (terminalRenderer) Clear() {
+	ui.Clear()
+}
+
+func (terminalRenderer) Size() (int, int) {
+	return ui.TerminalDimensions()
+}
+
+// bufferRenderer draws into a cell buffer of a fixed size, for screenshots and tests
+type bufferRenderer struct {
+	buf *ui.Buffer
+}
+
+func newBufferRenderer(width int, height int) *bufferRenderer {
+	return &bufferRenderer{buf: ui.NewBuffer(img.Rect(0, 0, width, height))}
+}
+
+// Render draws the items the way ui.Render does, clipping each one to its own rect and the screen
+func (r *bufferRenderer) Render(items ...ui.Drawable) {
+	for _, item := range items {
+		buf := ui.NewBuffer(item.GetRect())
+		item.Lock()
+		item.Draw(buf)
+		item.Unlock()
+		for point, cell := range buf.CellMap {
+			if point.In(buf.Rectangle) && point.In(r.buf.Rectangle) {
+				r.buf.SetCell(cell, point)
+			}
+		}
+	}
+}
+
+func (r *bufferRenderer) Clear() {
+	r.buf.Fill(ui.CellClear, r.buf.Rectangle)
+}
+
+func (r *bufferRenderer) Size() (int, int) {
+	return r.buf.Dx(), r.buf.Dy()
+}
+
+// Cell returns what was drawn at the point
+func (r *bufferRenderer) Cell(x int, y int) ui.Cell {
+	return r.buf.GetCell(img.Pt(x, y))
+}
+
+// String returns the screen as plain text, one line per row without trailing spaces
+func (r *bufferRenderer) String() string {
+	var (
+		lines = make([]string, 0, r.buf.Dy())
+		line  = make([]rune, r.buf.Dx())
+	)
+	for y := r.buf.Min.Y; y < r.buf.Max.Y; y++ {
+		for x := r.buf.Min.X; x < r.buf.Max.X; x++ {
+			ch := r.buf.GetCell(img.Pt(x, y)).Rune
+			if ch == 0 {
+				ch = ' '
+			}
+			line[x-r.buf.Min.X] = ch
+		}
+		lines = append(lines, strings.TrimRight(string(line), " "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// how often the replay clock moves on
+const replayTick = 100 * time.Millisecond
+
+const (
+	replaySeekStep = 10 * time.Second
+	replayMaxSpeed = 64.0
+	replayMinSpeed = 1.0 / 8
+)
+
+// replayer plays a recording back as the events the stats listeners send, at an adjustable speed. It can be
+// paused and seeked while playing.
+type replayer struct {
+	rec  *recording
+	done chan struct{}
+
+	mutex   sync.Mutex
+	history *statsHistory
+	// position in the recording
+	pos    time.Duration
+	speed  float64
+	paused bool
+	// set while a seek waits for the next tick
+	seekTo  time.Duration
+	seeking bool
+
+	// read and written by the play routine only
+	next int
+	sent map[string]bool
+}
+
+func newReplayer(rec *recording) *replayer {
+	return &replayer{
+		rec:     rec,
+		history: newStatsHistory(),
+		done:    make(chan struct{}),
+		speed:   1,
+		sent:    make(map[string]bool),
+	}
+}
+
+// listeners stands in for the recorded hosts, without connections
+func (p *replayer) listeners() listenerSet {
+	if len(p.rec.Hosts) == 0 {
+		return listenerSet{&StatsListener{}}
+	}
+	ls := make(listenerSet, len(p.rec.Hosts))
+	for i, host := range p.rec.Hosts {
+		ls[i] = &StatsListener{Host: host}
+	}
+	return ls
+}
+
+// Open starts playing the recording
+func (p *replayer) Open(events chan<- event) {
+	go func() {
+		ticker := time.NewTicker(replayTick)
+		defer ticker.Stop()
+		for {
+			p.moveTo(p.advance(), events)
+			select {
+			case <-ticker.C:
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+func (p *replayer) Close() {
+	close(p.done)
+}
+
+// advance works out where the recording should be after a tick
+func (p *replayer) advance() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	switch {
+	case p.seeking:
+		p.seeking = false
+		p.pos = p.seekTo
+	case !p.paused:
+		p.pos += time.Duration(float64(replayTick) * p.speed)
+	}
+	if end := p.rec.length(); p.pos > end {
+		p.pos = end
+	}
+	return p.pos
+}
+
+// moveTo sends what happened up to pos. Going back in time, the containers and stats are sent as they were at
+// pos instead, the event log only ever moves forward.
+func (p *replayer) moveTo(pos time.Duration, events chan<- event) {
+	if p.next > 0 && p.rec.entries[p.next-1].offset() > pos {
+		p.rewind(pos, events)
+		return
+	}
+	for ; p.next < len(p.rec.entries) && p.rec.entries[p.next].offset() <= pos; p.next++ {
+		entry := p.rec.entries[p.next]
+		switch {
+		case entry.Container != nil:
+			p.sent[entry.Container.ID] = true
+			events <- containerUpdated{Container: p.container(entry)}
+		case len(entry.Removed) > 0:
+			delete(p.sent, entry.Removed)
+			p.history.Remove(entry.Removed)
+			events <- containerRemoved{entry.Removed}
+		case entry.Stats != nil:
+			p.addHistory(entry)
+			events <- statsUpdated{*entry.Stats}
+		case entry.Event != nil:
+			events <- eventLogged{*entry.Event}
+		}
+	}
+}
+
+func (p *replayer) rewind(pos time.Duration, events chan<- event) {
+	var (
+		containers = make(map[string]recordedEntry)
+		stats      = make(map[string]StatsMsg)
+		hosts      = p.rec.Hosts
+	)
+	p.mutex.Lock()
+	p.history = newStatsHistory()
+	p.mutex.Unlock()
+	for p.next = 0; p.next < len(p.rec.entries) && p.rec.entries[p.next].offset() <= pos; p.next++ {
+		entry := p.rec.entries[p.next]
+		switch {
+		case entry.Container != nil:
+			containers[entry.Container.ID] = entry
+		case len(entry.Removed) > 0:
+			delete(containers, entry.Removed)
+			p.history.Remove(entry.Removed)
+		case entry.Stats != nil:
+			stats[entry.Stats.Host] = *entry.Stats
+			p.addHistory(entry)
+		}
+	}
+
+	for _, id := range sortedKeys(p.sent) {
+		if _, ok := containers[id]; !ok {
+			delete(p.sent, id)
+			events <- containerRemoved{id}
+		}
+	}
+	for _, id := range sortedKeys(containers) {
+		p.sent[id] = true
+		events <- containerUpdated{Container: p.container(containers[id])}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	for _, host := range hosts {
+		if _, ok := stats[host]; !ok {
+			// clears the host's stats from later on
+			stats[host] = StatsMsg{Host: host}
+		}
+	}
+	for _, host := range sortedKeys(stats) {
+		events <- statsUpdated{stats[host]}
+	}
+}
+
+// container shifts the recorded container's times by how long ago it was recorded, so uptimes read as they did
+func (p *replayer) container(entry recordedEntry) container {
+	var (
+		cont = *entry.Container
+		ago  = time.Since(p.rec.Started.Add(entry.offset()))
+	)
+	shift := func(t time.Time) time.Time {
+		if t.IsZero() {
+			return t
+		}
+		return t.Add(ago)
+	}
+	cont.Created = shift(cont.Created)
+	cont.State.StartedAt = shift(cont.State.StartedAt)
+	cont.State.FinishedAt = shift(cont.State.FinishedAt)
+	return container{Container: &cont, host: entry.Host}
+}
+
+func (p *replayer) addHistory(entry recordedEntry) {
+	at := p.rec.Started.Add(entry.offset())
+	for _, cs := range entry.Stats.Containers {
+		p.history.Add(cs.ID, statsSample{at, cs.CPUPercent, cs.MemPercent})
+	}
+}
+
+// History returns the samples recorded for the container within the window up to the replay position
+func (p *replayer) History(id string, window time.Duration) []statsSample {
+	p.mutex.Lock()
+	var (
+		history = p.history
+		now     = p.rec.Started.Add(p.pos)
+	)
+	p.mutex.Unlock()
+	return history.Since(id, now.Add(-window))
+}
+
+func (p *replayer) TogglePause() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.paused = !p.paused
+}
+
+// Seek moves the position by d, in either direction
+func (p *replayer) Seek(d time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.seeking {
+		p.seekTo = p.pos
+	}
+	p.seekTo += d
+	if p.seekTo < 0 {
+		p.seekTo = 0
+	}
+	if end := p.rec.length(); p.seekTo > end {
+		p.seekTo = end
+	}
+	p.seeking = true
+}
+
+// ChangeSpeed multiplies the speed by factor, within replayMinSpeed and replayMaxSpeed
+func (p *replayer) ChangeSpeed(factor float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.speed *= factor
+	if p.speed > replayMaxSpeed {
+		p.speed = replayMaxSpeed
+	}
+	if p.speed < replayMinSpeed {
+		p.speed = replayMinSpeed
+	}
+}
+
+// String describes the position, as the recorded time, how far in and the speed
+func (p *replayer) String() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	text := fmt.Sprintf("Replay %s %s/%s %gx", p.rec.Started.Add(p.pos).Format("15:04:05"),
+		clockString(p.pos), clockString(p.rec.length()), p.speed)
+	switch {
+	case p.paused:
+		text += " paused"
+	case p.pos >= p.rec.length():
+		text += " ended"
+	}
+	return text
+}
+
+// clockString formats a duration as [h:]mm:ss
+func clockString(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%02d:%02d", s/60, s%60)
+}
+
+// handleKey applies the replay controls, returning false for keys that aren't one
+func (p *replayer) handleKey(e uiEvent) bool {
+	switch e {
+	case KeySpace:
+		p.TogglePause()
+	case KeyLeftBracket:
+		p.Seek(-replaySeekStep)
+	case KeyRightBracket:
+		p.Seek(replaySeekStep)
+	case KeyMinus:
+		p.ChangeSpeed(0.5)
+	case KeyPlus:
+		p.ChangeSpeed(2)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+)
+
+type closingBuffer struct {
+	bytes.Buffer
+}
+
+func (b *closingBuffer) Close() error {
+	return nil
+}
+
+// testRecording records web starting, two rounds of stats a second apart, then web being removed
+func testRecording(t *testing.T) *closingBuffer {
+	t.Helper()
+	var (
+		buf      = &closingBuffer{}
+		rec      = newRecorder(buf, []string{"alpha"}, time.Now())
+		conts, _ = testContainers()
+		web      = conts[fakeID("a")]
+	)
+	web.host = "alpha"
+
+	at := func(d time.Duration) {
+		// entries are stamped with the time since the recording started
+		rec.started = time.Now().Add(-d)
+	}
+	at(0)
+	rec.Container(web)
+	at(time.Second)
+	rec.Stats(StatsMsg{Host: "alpha", Containers: []ContainerStats{{Host: "alpha", ID: web.ID, CPUPercent: 10}}})
+	rec.Tick()
+	at(2 * time.Second)
+	rec.Event(eventLogEntry{Host: "alpha", Type: "container", Action: "die", ID: web.ID})
+	// only the latest stats of a tick are kept
+	rec.Stats(StatsMsg{Host: "alpha", Containers: []ContainerStats{{Host: "alpha", ID: web.ID, CPUPercent: 50}}})
+	rec.Stats(StatsMsg{Host: "alpha", Containers: []ContainerStats{{Host: "alpha", ID: web.ID, CPUPercent: 90}}})
+	rec.Tick()
+	at(3 * time.Second)
+	rec.Removed(web.ID)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	recording, err := readRecording(testRecording(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recording.Hosts) != 1 || recording.Hosts[0] != "alpha" {
+		t.Errorf("expected the hosts in the header, got %v", recording.Hosts)
+	}
+	if len(recording.entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(recording.entries))
+	}
+	if first := recording.entries[0]; first.Container == nil || first.Host != "alpha" || first.At != 0 {
+		t.Errorf("expected web on alpha first, got %+v", first)
+	}
+	if length := recording.length(); length != 3*time.Second {
+		t.Errorf("expected a 3s recording, got %v", length)
+	}
+}
+
+func TestRecordingLeavesOutSecrets(t *testing.T) {
+	var (
+		buf = &closingBuffer{}
+		rec = newRecorder(buf, nil, time.Now())
+		web = testContainer("a", "web", "nginx", true, time.Hour, map[string]string{"tier": "front"})
+	)
+	web.Config.Env = []string{"DB_PASSWORD=hunter2"}
+	web.Config.Entrypoint = []string{"/entrypoint.sh"}
+	web.HostConfig.Binds = []string{"/srv/web:/usr/share/nginx/html"}
+	web.HostConfig.RestartPolicy = goDocker.RestartPolicy{Name: "always"}
+	rec.Container(web)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zip, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written, err := io.ReadAll(zip); err != nil || strings.Contains(string(written), "hunter2") {
+		t.Fatalf("expected the environment to be left out of the file, got %v:\n%s", err, written)
+	}
+
+	recording, err := readRecording(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := recording.entries[0].Container
+	if len(recorded.Config.Env) != 0 || recorded.HostConfig.RestartPolicy.Name != "" {
+		t.Errorf("expected the environment and host config to be left out, got %+v and %+v", recorded.Config, recorded.HostConfig)
+	}
+	if recorded.Config.Image != "nginx" || recorded.Config.Labels["tier"] != "front" ||
+		recorded.Config.Entrypoint[0] != "/entrypoint.sh" || recorded.HostConfig.Binds[0] != "/srv/web:/usr/share/nginx/html" {
+		t.Errorf("expected the fields the dashboard shows to be kept, got %+v and %+v", recorded.Config, recorded.HostConfig)
+	}
+	if web.Config.Env[0] != "DB_PASSWORD=hunter2" {
+		t.Error("expected the container itself to be left alone")
+	}
+}
+
+func TestReadRecordingCutShort(t *testing.T) {
+	var (
+		buf = &closingBuffer{}
+		rec = newRecorder(buf, nil, time.Now())
+	)
+	rec.Stats(StatsMsg{Containers: []ContainerStats{{ID: "1"}}})
+	rec.Tick()
+	rec.Stats(StatsMsg{Containers: []ContainerStats{{ID: "2"}}})
+	rec.Tick()
+
+	// a recording that was never closed ends without the gzip footer
+	recording, err := readRecording(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recording.entries) != 2 {
+		t.Errorf("expected both flushed entries, got %d", len(recording.entries))
+	}
+
+	if _, err := readRecording(bytes.NewReader([]byte("not gzip"))); err == nil {
+		t.Error("expected an error reading something that isn't a recording")
+	}
+}
+
+// replayQueue is buffered so the replayer can be stepped without a model reading from it
+type replayQueue chan event
+
+func newReplayQueue() replayQueue {
+	return make(replayQueue, 100)
+}
+
+func (c replayQueue) moveTo(p *replayer, pos time.Duration) {
+	p.mutex.Lock()
+	p.pos = pos
+	p.mutex.Unlock()
+	p.moveTo(pos, c)
+}
+
+func (c replayQueue) drain() (conts []container, removed []string, stats []StatsMsg, events []eventLogEntry) {
+	for {
+		select {
+		case e := <-c:
+			switch e := e.(type) {
+			case containerUpdated:
+				conts = append(conts, e.Container)
+			case containerRemoved:
+				removed = append(removed, e.ID)
+			case statsUpdated:
+				stats = append(stats, e.Stats)
+			case eventLogged:
+				events = append(events, e.Entry)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestReplayForwardAndBack(t *testing.T) {
+	recording, err := readRecording(testRecording(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		p     = newReplayer(recording)
+		queue = newReplayQueue()
+		id    = fakeID("a")
+	)
+
+	queue.moveTo(p, 2*time.Second)
+	conts, removed, stats, events := queue.drain()
+	if len(conts) != 1 || conts[0].host != "alpha" || len(removed) != 0 || len(stats) != 2 || len(events) != 1 {
+		t.Fatalf("expected web, two stats and an event, got %d, %d, %d, %d", len(conts), len(removed), len(stats), len(events))
+	}
+	// the uptime reads as it did when recorded, three hours
+	if uptime := time.Since(conts[0].State.StartedAt); uptime < 3*time.Hour-time.Minute || uptime > 3*time.Hour+time.Minute {
+		t.Errorf("expected the uptime to be shifted to 3h, got %v", uptime)
+	}
+	if samples := p.History(id, time.Minute); len(samples) != 2 || samples[1].CPU != 90 {
+		t.Errorf("expected both samples in the history, got %+v", samples)
+	}
+
+	queue.moveTo(p, 3*time.Second)
+	if _, removed, _, _ := queue.drain(); len(removed) != 1 || removed[0] != id {
+		t.Errorf("expected web to be removed, got %v", removed)
+	}
+
+	// seeking back sends web again with the stats as they were
+	queue.moveTo(p, time.Second)
+	conts, removed, stats, events = queue.drain()
+	if len(conts) != 1 || len(removed) != 0 || len(events) != 0 {
+		t.Errorf("expected web to be sent again, got %d containers, %d removed, %d events", len(conts), len(removed), len(events))
+	}
+	if len(stats) != 1 || stats[0].Containers[0].CPUPercent != 10 {
+		t.Errorf("expected the first round of stats, got %+v", stats)
+	}
+	if samples := p.History(id, time.Minute); len(samples) != 1 {
+		t.Errorf("expected the history to be rewound to one sample, got %+v", samples)
+	}
+
+	// back to the start, before web was created
+	queue.moveTo(p, 0)
+	queue.drain()
+	queue.moveTo(p, -time.Millisecond)
+	conts, removed, stats, _ = queue.drain()
+	if len(conts) != 0 || len(removed) != 1 || len(stats) != 1 || len(stats[0].Containers) != 0 {
+		t.Errorf("expected web to be removed and alpha's stats cleared, got %d, %v, %+v", len(conts), removed, stats)
+	}
+}
+
+func TestReplayControls(t *testing.T) {
+	recording, err := readRecording(testRecording(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newReplayer(recording)
+
+	p.Seek(replaySeekStep)
+	if pos := p.advance(); pos != recording.length() {
+		t.Errorf("seeking past the end should stop at the end, got %v", pos)
+	}
+	p.Seek(-time.Second)
+	p.Seek(-time.Second)
+	if pos := p.advance(); pos != time.Second {
+		t.Errorf("seeks before a tick should add up, got %v", pos)
+	}
+
+	p.TogglePause()
+	if pos := p.advance(); pos != time.Second {
+		t.Errorf("a paused replay shouldn't move, got %v", pos)
+	}
+	p.TogglePause()
+	p.ChangeSpeed(2)
+	if pos := p.advance(); pos != time.Second+2*replayTick {
+		t.Errorf("expected to move two ticks at double speed, got %v", pos)
+	}
+
+	for i := 0; i < 20; i++ {
+		p.ChangeSpeed(2)
+	}
+	if p.speed != replayMaxSpeed {
+		t.Errorf("expected the speed to stop at %v, got %v", replayMaxSpeed, p.speed)
+	}
+	if !p.handleKey(KeyMinus) || p.handleKey(KeyQ) {
+		t.Error("expected - to be a replay control and q not to be")
+	}
+}
+
+func TestClockString(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                          "00:00",
+		83 * time.Second:           "01:23",
+		time.Hour + 2*time.Minute:  "1:02:00",
+		10*time.Hour + time.Second: "10:00:01",
+	} {
+		if got := clockString(d); got != want {
+			t.Errorf("clockString(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// the smallest screen the grid lays out on
+const minScreenWidth, minScreenHeight = 21, 12
+
+type screenshotOptions struct {
+	width     int
+	height    int
+	showAll   bool
+	grouped   bool
+	tableMode bool
+	columns   []column
+	infoType  dockerInfoType
+	order     sortOrder
+	filters   containerFilters
+}
+
+// parseScreenSize reads a size given as WIDTHxHEIGHT
+func parseScreenSize(size string) (width int, height int, err error) {
+	w, h, found := strings.Cut(strings.ToLower(size), "x")
+	if !found {
+		return 0, 0, fmt.Errorf("bad screen size %q, expected WIDTHxHEIGHT", size)
+	}
+	if width, err = strconv.Atoi(w); err != nil {
+		return 0, 0, fmt.Errorf("bad screen width %q", w)
+	}
+	if height, err = strconv.Atoi(h); err != nil {
+		return 0, 0, fmt.Errorf("bad screen height %q", h)
+	}
+	if width < minScreenWidth || height < minScreenHeight {
+		return 0, 0, fmt.Errorf("screen size %s is too small, it has to be at least %dx%d", size, minScreenWidth, minScreenHeight)
+	}
+	return width, height, nil
+}
+
+// screenshot draws the container screen into a buffer, the way the dashboard first shows it
+func screenshot(ls listenerSet, containers containerMap, stats StatsMsg, opts screenshotOptions) *bufferRenderer {
+	var (
+		screen = newBufferRenderer(opts.width, opts.height)
+		v      = NewView(screen)
+		byID   = stats.byID()
+		sorted = opts.filters.apply(containers.visible(opts.showAll || opts.filters.hasStatus())).sorted(opts.order, byID)
+		rows   = flatRows(sorted)
+		window = historyWindows[0]
+	)
+	if opts.grouped {
+		rows = groupedRows(sorted, nil)
+	}
+
+	v.SetColumns(opts.columns)
+	v.SetTableMode(opts.tableMode)
+	v.SetLayout()
+	v.SetHosts(ls.hostNames())
+
+	if cont, ok := rows.selected(0); ok {
+		v.UpdateHistory(cont.shortName(), ls.forHost(cont.host).History(cont.ID, window), window)
+	} else {
+		v.UpdateHistory("", nil, window)
+	}
+	v.RenderContainers(rows, byID, opts.infoType, 0, false, opts.order)
+	v.UpdateInfoBar(containers, &stats)
+	return screen
+}
+
+// runScreenshot prints the container screen as plain text once every running container has stats
+func runScreenshot(ls listenerSet, out io.Writer, opts screenshotOptions) error {
+	return followRounds(ls, 1, func(containers containerMap, stats StatsMsg) error {
+		_, err := io.WriteString(out, screenshot(ls, containers, stats, opts).String())
+		return err
+	})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	units "github.com/docker/go-units"
+)
+
+// how long to wait for every container to report stats before printing a round anyway
+const snapshotRoundTimeout = 5 * time.Second
+
+var snapshotHeaders = []string{"name", "id", "image", "status", "cpu_percent", "mem_percent", "mem_usage", "mem_limit",
+	"net_rx_rate", "net_tx_rate", "block_read_rate", "block_write_rate", "ports"}
+
+type snapshotRow struct {
+	ContainerStats
+	Status string   `json:"status"`
+	Ports  []string `json:"ports"`
+}
+
+func snapshotRows(containers containerMap, stats []ContainerStats, order sortOrder) []snapshotRow {
+	var (
+		byID = make(map[string]ContainerStats, len(stats))
+		rows = []snapshotRow{}
+	)
+	for _, cs := range stats {
+		byID[cs.ID] = cs
+	}
+
+	for _, cont := range containers.sorted(order, byID) {
+		cs, ok := byID[cont.ID]
+		if !ok {
+			cs = ContainerStats{Host: cont.host, ID: cont.ID, Name: cont.shortName(), Image: cont.imageName()}
+		}
+		rows = append(rows, snapshotRow{cs, cont.status(), cont.ports()})
+	}
+	return rows
+}
+
+// sampled tells if every running container has had the given number of stats samples
+func sampled(containers containerMap, stats []ContainerStats, samples int) bool {
+	byID := make(map[string]int, len(stats))
+	for _, cs := range stats {
+		byID[cs.ID] = cs.Samples
+	}
+	for id, cont := range containers {
+		if cont.running() && byID[id] < samples {
+			return false
+		}
+	}
+	return true
+}
+
+type snapshotWriter struct {
+	out    io.Writer
+	format string
+	rounds int
+	// adds a host column to csv and table output
+	hosts bool
+}
+
+func newSnapshotWriter(out io.Writer, format string, hosts bool) (*snapshotWriter, error) {
+	switch format {
+	case "json", "csv", "table":
+		return &snapshotWriter{out: out, format: format, hosts: hosts}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, expected json, csv or table", format)
+}
+
+func (w *snapshotWriter) Write(rows []snapshotRow) error {
+	defer func() { w.rounds++ }()
+	switch w.format {
+	case "json":
+		return json.NewEncoder(w.out).Encode(rows)
+	case "csv":
+		return w.writeCSV(rows)
+	default:
+		return w.writeTable(rows)
+	}
+}
+
+func (w *snapshotWriter) writeCSV(rows []snapshotRow) error {
+	cw := csv.NewWriter(w.out)
+	if w.rounds == 0 {
+		headers := snapshotHeaders
+		if w.hosts {
+			headers = append([]string{"host"}, headers...)
+		}
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		record := []string{
+			row.Name,
+			row.ID,
+			row.Image,
+			row.Status,
+			strconv.FormatFloat(row.CPUPercent, 'f', 1, 64),
+			strconv.FormatFloat(row.MemPercent, 'f', 1, 64),
+			strconv.FormatUint(row.MemUsage, 10),
+			strconv.FormatUint(row.MemLimit, 10),
+			strconv.FormatFloat(row.NetRxRate, 'f', 0, 64),
+			strconv.FormatFloat(row.NetTxRate, 'f', 0, 64),
+			strconv.FormatFloat(row.BlockReadRate, 'f', 0, 64),
+			strconv.FormatFloat(row.BlockWriteRate, 'f', 0, 64),
+			strings.Join(row.Ports, " "),
+		}
+		if w.hosts {
+			record = append([]string{row.Host}, record...)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (w *snapshotWriter) writeTable(rows []snapshotRow) error {
+	if w.rounds > 0 {
+		fmt.Fprintln(w.out)
+	}
+	tw := tabwriter.NewWriter(w.out, 0, 4, 2, ' ', 0)
+	if w.hosts {
+		fmt.Fprint(tw, "HOST\t")
+	}
+	fmt.Fprintln(tw, "NAME\tID\tIMAGE\tSTATUS\tCPU %\tMEM %\tMEM USAGE / LIMIT\tNET RX / TX\tBLOCK READ / WRITE\tPORTS")
+	for _, row := range rows {
+		if w.hosts {
+			fmt.Fprint(tw, row.Host+"\t")
+		}
+		fmt.Fprintf(tw, "%s\t%.12s\t%s\t%s\t%.1f\t%.1f\t%s / %s\t%s / %s\t%s / %s\t%s\n",
+			row.Name,
+			row.ID,
+			row.Image,
+			row.Status,
+			row.CPUPercent,
+			row.MemPercent,
+			units.BytesSize(float64(row.MemUsage)),
+			units.BytesSize(float64(row.MemLimit)),
+			rateString(row.NetRxRate),
+			rateString(row.NetTxRate),
+			rateString(row.BlockReadRate),
+			rateString(row.BlockWriteRate),
+			strings.Join(row.Ports, ","),
+		)
+	}
+	return tw.Flush()
+}
+
+type snapshotOptions struct {
+	format  string
+	rounds  int
+	showAll bool
+	order   sortOrder
+	filters containerFilters
+}
+
+// runSnapshot prints the given number of rounds of stats without starting the ui
+func runSnapshot(ls listenerSet, out io.Writer, opts snapshotOptions) error {
+	writer, err := newSnapshotWriter(out, opts.format, len(ls.hostNames()) > 0)
+	if err != nil {
+		return err
+	}
+	return followRounds(ls, opts.rounds, func(containers containerMap, stats StatsMsg) error {
+		visible := opts.filters.apply(containers.visible(opts.showAll || opts.filters.hasStatus()))
+		return writer.Write(snapshotRows(visible, stats.Containers, opts.order))
+	})
+}
+
+// followRounds opens the listeners and passes the containers and stats to write for the given number of rounds.
+// A round is complete once every running container has sent a fresh sample.
+func followRounds(ls listenerSet, rounds int, write func(containers containerMap, stats StatsMsg) error) error {
+	var (
+		events   = make(chan event)
+		opened   = make(chan error, 1)
+		ready    <-chan struct{}
+		isReady  = false
+		m        = newModel()
+		timeout  <-chan time.Time
+		timedOut = false
+		written  = 0
+	)
+
+	go func() {
+		opened <- ls.Open(events)
+	}()
+	defer ls.Close()
+
+	for {
+		select {
+		case err := <-opened:
+			if err != nil {
+				return err
+			}
+			ready = ls.Ready()
+			opened = nil
+		case <-ready:
+			isReady = true
+			ready = nil
+			timeout = time.After(snapshotRoundTimeout)
+		case e := <-events:
+			m.apply(e)
+			// until the listeners are open, a host that can't be reached fails the snapshot instead
+			if e, ok := e.(connChanged); ok && e.Status.Err != nil && opened == nil {
+				fmt.Fprintln(os.Stderr, e.Status)
+			}
+		case <-timeout:
+			timedOut = true
+		}
+
+		if !isReady {
+			continue
+		}
+		var stats StatsMsg
+		if m.stats != nil {
+			stats = *m.stats
+		}
+		// the first sample of a stream has no previous cpu reading, so it doesn't count
+		if !timedOut && !sampled(m.containers, stats.Containers, written+2) {
+			continue
+		}
+
+		if err := write(m.containers, stats); err != nil {
+			return err
+		}
+		if written++; written >= rounds {
+			return nil
+		}
+		timeout = time.After(snapshotRoundTimeout)
+		timedOut = false
+	}
+}
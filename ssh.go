@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/byrnedo/dockdash/logger"
+)
+
+// long enough to type in a password or passphrase
+const sshTunnelTimeout = time.Minute
+
+const defaultRemoteSocket = "/var/run/docker.sock"
+
+// the ssh client to run, a fake one in the tests
+var sshCommand = "ssh"
+
+var errTunnelClosed = errors.New("ssh tunnel closed")
+
+// sshTunnel forwards a local unix socket to the docker socket of a remote host, using the ssh command so the
+// user's ssh config, keys and agent all apply. The socket is in a directory only the user can open, anyone who can
+// connect to it has the run of the remote daemon. The socket stays at the same path when ssh is run again after
+// exiting, so a client connected to it keeps working.
+type sshTunnel struct {
+	args  []string
+	dir   string
+	local string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	exited chan struct{}
+	closed chan struct{}
+	// set after the first run, once the dashboard has the terminal and ssh can't ask for a password
+	batch bool
+}
+
+// newSSHTunnel sets up tunnelling to an ssh://[user@]host[:port][/path/to/docker.sock] endpoint, without running
+// ssh yet
+func newSSHTunnel(endpoint string) (*sshTunnel, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Hostname()) == 0 {
+		return nil, fmt.Errorf("no host in %s", endpoint)
+	}
+
+	remote := u.Path
+	if len(remote) == 0 {
+		remote = defaultRemoteSocket
+	}
+	// created 0700
+	dir, err := os.MkdirTemp("", "dockdash-ssh-")
+	if err != nil {
+		return nil, err
+	}
+	local := filepath.Join(dir, "docker.sock")
+
+	args := []string{"-n", "-N", "-T", "-o", "ExitOnForwardFailure=yes", "-o", "StreamLocalBindMask=0177", "-L", local + ":" + remote}
+	if port := u.Port(); len(port) > 0 {
+		args = append(args, "-p", port)
+	}
+	target := u.Hostname()
+	if u.User != nil {
+		target = u.User.Username() + "@" + target
+	}
+	args = append(args, "--", target)
+	return &sshTunnel{args: args, dir: dir, local: local, closed: make(chan struct{})}, nil
+}
+
+// open runs ssh, returning once the local end accepts connections
+func (t *sshTunnel) open() error {
+	t.mu.Lock()
+	select {
+	case <-t.closed:
+		t.mu.Unlock()
+		return errTunnelClosed
+	default:
+	}
+	args := t.args
+	if t.batch {
+		args = append([]string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}, args...)
+	}
+	t.batch = true
+	// left behind by an ssh that died, it would stop the new one from listening
+	os.Remove(t.local)
+
+	var (
+		cmd    = exec.Command(sshCommand, args...)
+		stderr = &bytes.Buffer{}
+		exited = make(chan struct{})
+	)
+	cmd.Stderr = stderr
+	Info.Println("opening ssh tunnel:", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		t.mu.Unlock()
+		return fmt.Errorf("failed to run ssh: %w", err)
+	}
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	t.cmd, t.exited = cmd, exited
+	t.mu.Unlock()
+
+	if err := t.wait(exited, stderr); err != nil {
+		t.kill()
+		return err
+	}
+	return nil
+}
+
+// ensureOpen runs ssh again if it has exited since the tunnel was last opened
+func (t *sshTunnel) ensureOpen() error {
+	t.mu.Lock()
+	exited := t.exited
+	t.mu.Unlock()
+	if exited != nil {
+		select {
+		case <-exited:
+		default:
+			return nil
+		}
+	}
+	return t.open()
+}
+
+func (t *sshTunnel) wait(exited <-chan struct{}, stderr *bytes.Buffer) error {
+	var (
+		timeout = time.After(sshTunnelTimeout)
+		ticker  = time.NewTicker(100 * time.Millisecond)
+	)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return fmt.Errorf("ssh exited: %s", strings.TrimSpace(stderr.String()))
+		case <-t.closed:
+			return errTunnelClosed
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for ssh to connect")
+		case <-ticker.C:
+			if conn, err := net.Dial("unix", t.local); err == nil {
+				conn.Close()
+				return nil
+			}
+		}
+	}
+}
+
+// kill stops ssh, if it is running
+func (t *sshTunnel) kill() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cmd == nil {
+		return
+	}
+	select {
+	case <-t.exited:
+	default:
+		t.cmd.Process.Kill()
+		<-t.exited
+	}
+}
+
+// endpoint is the local end of the tunnel, to connect the docker client to
+func (t *sshTunnel) endpoint() string {
+	return "unix://" + t.local
+}
+
+func (t *sshTunnel) Close() {
+	t.mu.Lock()
+	select {
+	case <-t.closed:
+		t.mu.Unlock()
+		return
+	default:
+		close(t.closed)
+	}
+	t.mu.Unlock()
+	t.kill()
+	os.RemoveAll(t.dir)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSSHEnv names the directory the test binary, run as ssh, is scripted from
+const fakeSSHEnv = "DOCKDASH_FAKE_SSH"
+
+// fakeSSH stands in for ssh: it listens on the local end of the -L forward, accepting and dropping connections,
+// until it is killed. While the directory has a file named down it fails to connect instead.
+func fakeSSH(dir string, args []string) int {
+	if _, err := os.Stat(filepath.Join(dir, "down")); err == nil {
+		fmt.Fprintln(os.Stderr, "ssh: connect to host build port 22: Connection refused")
+		return 255
+	}
+	var local string
+	for i, arg := range args {
+		if arg == "-L" && i+1 < len(args) {
+			local, _, _ = strings.Cut(args[i+1], ":")
+		}
+	}
+	listener, err := net.Listen("unix", local)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 255
+	}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return 255
+		}
+		conn.Close()
+	}
+}
+
+// useFakeSSH runs the test binary as ssh, returning the path of the file that makes it fail to connect
+func useFakeSSH(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(fakeSSHEnv, dir)
+	command := sshCommand
+	sshCommand = os.Args[0]
+	t.Cleanup(func() { sshCommand = command })
+	return filepath.Join(dir, "down")
+}
+
+func TestListenerReopensADeadSSHTunnel(t *testing.T) {
+	if testing.Short() {
+		t.Skip("waits for the reconnect delay")
+	}
+	down := useFakeSSH(t)
+	if err := os.WriteFile(down, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tunnel, err := newSSHTunnel("ssh://me@build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	openErr := tunnel.open()
+	if openErr == nil || !strings.Contains(openErr.Error(), "Connection refused") {
+		t.Fatalf("expected ssh to fail to connect, got %v", openErr)
+	}
+
+	docker := newFakeDocker()
+	docker.create(fakeID("a"), "web", "busybox")
+	docker.start(fakeID("a"))
+	l, err := startStatsListener(t, docker, &StatsListener{DockerClient: docker, Host: "test", tunnel: tunnel, connectErr: openErr})
+	if err != openErr {
+		t.Fatalf("expected Open to return the tunnel's error, got %v", err)
+	}
+	if status := receive(t, l.status, "the disconnected status"); status.Err != openErr || status.Retry == 0 {
+		t.Errorf("expected the tunnel to be retried, got %+v", status)
+	}
+
+	// ssh can connect again
+	os.Remove(down)
+	if status := receive(t, l.status, "the reconnected status"); status.Err != nil {
+		t.Errorf("expected the tunnel to be opened again, got %+v", status)
+	}
+	receive(t, l.Ready(), "the listener to be ready")
+	receive(t, l.newConts, "the listed container")
+
+	// ssh dies, which ends the event stream, and can't connect for a while
+	if err := os.WriteFile(down, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tunnel.kill()
+	docker.closeEvents()
+	if status := receive(t, l.status, "the disconnected status"); status.Err != errEventsClosed {
+		t.Errorf("expected the event stream to end, got %+v", status)
+	}
+	if status := receive(t, l.status, "the failed retry"); status.Err == nil || !strings.Contains(status.Err.Error(), "Connection refused") {
+		t.Errorf("expected ssh to fail to connect on the retry, got %+v", status)
+	}
+	os.Remove(down)
+	if status := receive(t, l.status, "the reconnected status"); status.Err != nil {
+		t.Errorf("expected the tunnel to be opened again, got %+v", status)
+	}
+	if err := tunnel.ensureOpen(); err != nil {
+		t.Errorf("expected the tunnel to be running, got %v", err)
+	}
+
+	l.Close()
+	if _, err := os.Stat(tunnel.dir); !os.IsNotExist(err) {
+		t.Errorf("expected the socket's directory to be removed, got %v", err)
+	}
+}
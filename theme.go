@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+// colorTheme names the colors used across the dashboard, either termui color names or 256 color numbers
+type colorTheme struct {
+	Title    string `yaml:"title"`
+	Text     string `yaml:"text"`
+	Chart    string `yaml:"chart"`
+	Project  string `yaml:"project"`
+	Stopped  string `yaml:"stopped"`
+	Error    string `yaml:"error"`
+	Warning  string `yaml:"warning"`
+	Critical string `yaml:"critical"`
+	// set for themes without colors, where warning and critical are shown in bold and reversed instead
+	emphasis bool
+}
+
+var themes = map[string]colorTheme{
+	"dark": {
+		Title:    "green",
+		Text:     "cyan",
+		Chart:    "white",
+		Project:  "yellow",
+		Stopped:  "grey",
+		Error:    "red",
+		Warning:  "yellow",
+		Critical: "red",
+	},
+	"light": {
+		Title:    "blue",
+		Text:     "black",
+		Chart:    "blue",
+		Project:  "magenta",
+		Stopped:  "grey",
+		Error:    "red",
+		Warning:  "130",
+		Critical: "red",
+	},
+	"mono": {
+		Title:    "clear",
+		Text:     "clear",
+		Chart:    "clear",
+		Project:  "clear",
+		Stopped:  "clear",
+		Error:    "clear",
+		Warning:  "clear",
+		Critical: "clear",
+		emphasis: true,
+	},
+}
+
+// defaultThemeName is mono when NO_COLOR is set, see https://no-color.org
+func defaultThemeName() string {
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return "mono"
+	}
+	return "dark"
+}
+
+func findTheme(name string) (colorTheme, error) {
+	theme, ok := themes[name]
+	if !ok {
+		return colorTheme{}, fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(sortedKeys(themes), ", "))
+	}
+	return theme, nil
+}
+
+var (
+	titleStyle = ui.Style{Fg: ui.ColorGreen, Bg: ui.ColorClear}
+	textStyle  = ui.Style{Fg: ui.ColorCyan, Bg: ui.ColorClear}
+	chartColor = ui.ColorWhite
+	// indexed by alertLevel
+	alertStyles = []ui.Style{
+		{Fg: ui.ColorWhite, Bg: ui.ColorClear},
+		{Fg: ui.ColorYellow, Bg: ui.ColorClear},
+		{Fg: ui.ColorRed, Bg: ui.ColorClear},
+	}
+)
+
+// extraColors are names termui doesn't know
+var extraColors = map[string]ui.Color{
+	"grey": ui.Color(8),
+}
+
+func parseColor(name string) (ui.Color, error) {
+	if color, ok := ui.StyleParserColorMap[name]; ok {
+		return color, nil
+	}
+	if color, ok := extraColors[name]; ok {
+		return color, nil
+	}
+	if num, err := strconv.Atoi(name); err == nil && num >= 0 && num < 256 {
+		return ui.Color(num), nil
+	}
+	return ui.ColorClear, fmt.Errorf("unknown color %q, expected a color name or a number from 0 to 255", name)
+}
+
+// merge fills in the colors missing from t with those of other
+func (t colorTheme) merge(other colorTheme) colorTheme {
+	for _, pair := range []struct{ value, fallback *string }{
+		{&t.Title, &other.Title},
+		{&t.Text, &other.Text},
+		{&t.Chart, &other.Chart},
+		{&t.Project, &other.Project},
+		{&t.Stopped, &other.Stopped},
+		{&t.Error, &other.Error},
+		{&t.Warning, &other.Warning},
+		{&t.Critical, &other.Critical},
+	} {
+		if len(*pair.value) == 0 {
+			*pair.value = *pair.fallback
+		}
+	}
+	t.emphasis = other.emphasis
+	return t
+}
+
+// apply sets the styles the widgets are created with, and the project, stopped, error, warning and critical names
+// used in style markup. It has to be called before the view is created, the markup names are unset until it is.
+func (t colorTheme) apply() error {
+	colors := make(map[string]ui.Color)
+	for _, named := range []struct{ name, value string }{
+		{"title", t.Title},
+		{"text", t.Text},
+		{"chart", t.Chart},
+		{"project", t.Project},
+		{"stopped", t.Stopped},
+		{"error", t.Error},
+		{"warning", t.Warning},
+		{"critical", t.Critical},
+	} {
+		color, err := parseColor(named.value)
+		if err != nil {
+			return fmt.Errorf("%s color: %w", named.name, err)
+		}
+		colors[named.name] = color
+	}
+
+	titleStyle = ui.Style{Fg: colors["title"], Bg: ui.ColorClear}
+	textStyle = ui.Style{Fg: colors["text"], Bg: ui.ColorClear}
+	chartColor = colors["chart"]
+	if chartColor == ui.ColorClear {
+		// bars are drawn with the background color, which would hide them
+		chartColor = ui.ColorWhite
+	}
+	alertStyles = []ui.Style{
+		{Fg: chartColor, Bg: ui.ColorClear},
+		{Fg: colors["warning"], Bg: ui.ColorClear},
+		{Fg: colors["critical"], Bg: ui.ColorClear},
+	}
+	alertMarkup = []string{"", "fg:warning", "fg:critical"}
+	if t.emphasis {
+		alertStyles[WarningLevel].Modifier = ui.ModifierBold
+		alertStyles[CriticalLevel].Modifier = ui.ModifierReverse
+		alertMarkup = []string{"", "fg:warning,mod:bold", "fg:critical,mod:reverse"}
+	}
+	for _, name := range []string{"project", "stopped", "error", "warning", "critical"} {
+		ui.StyleParserColorMap[name] = colors[name]
+	}
+	return nil
+}
+
+// alertLevel is how far past its thresholds a container's usage is
+type alertLevel int
+
+const (
+	NormalLevel alertLevel = iota
+	WarningLevel
+	CriticalLevel
+)
+
+// style markup for each alertLevel, set by the theme
+var alertMarkup = []string{"", "fg:warning", "fg:critical"}
+
+// highlighted wraps text in style markup for the level, leaving it as it is when usage is normal
+func highlighted(text string, level alertLevel) string {
+	if level == NormalLevel || len(text) == 0 {
+		return text
+	}
+	return "[" + text + "](" + alertMarkup[level] + ")"
+}
+
+// plainText keeps text from docker, like log lines, from being read as style markup by swapping its square
+// brackets for parentheses. termui has no way to escape them, and drops characters of text it can't parse.
+func plainText(text string) string {
+	return markupReplacer.Replace(text)
+}
+
+var markupReplacer = strings.NewReplacer("[", "(", "]", ")")
+
+// threshold is the usage, in percent, at which a container is shown as a warning or as critical
+type threshold struct {
+	Warning  float64 `yaml:"warning"`
+	Critical float64 `yaml:"critical"`
+}
+
+type thresholds struct {
+	CPU threshold `yaml:"cpu"`
+	Mem threshold `yaml:"mem"`
+}
+
+var alertThresholds = thresholds{
+	CPU: threshold{Warning: 80, Critical: 95},
+	Mem: threshold{Warning: 80, Critical: 90},
+}
+
+// parseThreshold reads a warning,critical pair
+func parseThreshold(expr string) (t threshold, err error) {
+	warning, critical, found := strings.Cut(expr, ",")
+	if !found {
+		return t, fmt.Errorf("expected warning,critical, got %q", expr)
+	}
+	if t.Warning, err = strconv.ParseFloat(strings.TrimSpace(warning), 64); err != nil {
+		return
+	}
+	if t.Critical, err = strconv.ParseFloat(strings.TrimSpace(critical), 64); err != nil {
+		return
+	}
+	return t, t.validate()
+}
+
+func (t threshold) validate() error {
+	if t.Warning <= 0 || t.Critical < t.Warning {
+		return fmt.Errorf("thresholds have to be above 0, with critical no lower than warning")
+	}
+	return nil
+}
+
+func (t threshold) String() string {
+	return strconv.FormatFloat(t.Warning, 'f', -1, 64) + "," + strconv.FormatFloat(t.Critical, 'f', -1, 64)
+}
+
+func (t threshold) level(percent float64) alertLevel {
+	switch {
+	case percent >= t.Critical:
+		return CriticalLevel
+	case percent >= t.Warning:
+		return WarningLevel
+	}
+	return NormalLevel
+}
+
+// level is the higher of the cpu and memory levels
+func (t thresholds) level(stats ContainerStats) alertLevel {
+	cpu, mem := t.CPU.level(stats.CPUPercent), t.Mem.level(stats.MemPercent)
+	if cpu > mem {
+		return cpu
+	}
+	return mem
+}
@@ -2,19 +2,24 @@ package main
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 )
 
-var (
-	titleStyle = ui.Style{Fg: ui.ColorGreen, Bg: ui.ColorClear}
-)
-
 type uiEvent int
 
+// uiInput is a key press, or resize, along with the termui id it came from
+type uiInput struct {
+	Event uiEvent
+	ID    string
+}
+
 const (
-	KeyArrowUp uiEvent = 1 << iota
+	KeyNone uiEvent = iota
+	KeyArrowUp
 	KeyArrowDown
 	KeyArrowLeft
 	KeyArrowRight
@@ -23,6 +28,33 @@ const (
 	KeyQ
 	Resize
 	KeyI
+	KeyS
+	KeyT
+	KeyR
+	KeyK
+	KeyP
+	KeyU
+	KeyY
+	KeyN
+	KeyA
+	KeyTab
+	KeyL
+	KeySpace
+	KeyPageUp
+	KeyPageDown
+	KeyW
+	KeyO
+	KeyShiftO
+	KeySlash
+	KeyE
+	KeyG
+	KeyC
+	KeyV
+	KeyShiftE
+	KeyLeftBracket
+	KeyRightBracket
+	KeyMinus
+	KeyPlus
 )
 
 type dockerInfoType int
@@ -37,6 +69,9 @@ const (
 	EnvInfo
 	VolumesInfo
 	TimeInfo
+	StatusInfo
+	NetIOInfo
+	BlockIOInfo
 )
 
 var infoHeaders = map[dockerInfoType]string{
@@ -49,18 +84,86 @@ var infoHeaders = map[dockerInfoType]string{
 	EnvInfo:        "Envs",
 	VolumesInfo:    "Volumes",
 	TimeInfo:       "Created At",
+	StatusInfo:     "Status",
+	NetIOInfo:      "Net I/O",
+	BlockIOInfo:    "Block I/O",
 }
 
+// infoNames are the names --info and the config file use for the info columns
+var infoNames = map[dockerInfoType]string{
+	ImageInfo:      "image",
+	Names:          "names",
+	PortInfo:       "ports",
+	BindInfo:       "mounts",
+	CommandInfo:    "command",
+	EntrypointInfo: "entrypoint",
+	EnvInfo:        "envs",
+	VolumesInfo:    "volumes",
+	TimeInfo:       "created",
+	StatusInfo:     "status",
+	NetIOInfo:      "net",
+	BlockIOInfo:    "io",
+}
+
+func parseInfoType(name string) (dockerInfoType, error) {
+	for infoType, infoName := range infoNames {
+		if infoName == name {
+			return infoType, nil
+		}
+	}
+	names := make([]string, 0, len(infoNames))
+	for infoType := ImageInfo; infoType <= BlockIOInfo; infoType++ {
+		names = append(names, infoNames[infoType])
+	}
+	return ImageInfo, fmt.Errorf("unknown info column %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+type screen int
+
+const (
+	ContainerScreen screen = iota
+	ImageScreen
+	LogScreen
+	EventScreen
+)
+
 const maxContainers = 1000
-const maxHorizPos = int(TimeInfo)
+const flashDuration = 10 * time.Second
+const maxHorizPos = int(BlockIOInfo)
 
 type view struct {
-	Grid     *ui.Grid
-	InfoBar  *widgets.Paragraph
-	CpuChart *widgets.BarChart
-	MemChart *widgets.BarChart
-	NameList *widgets.List
-	InfoList *widgets.List
+	Grid       *ui.Grid
+	ImageGrid  *ui.Grid
+	LogGrid    *ui.Grid
+	EventGrid  *ui.Grid
+	InfoBar    *widgets.Paragraph
+	CpuChart   *widgets.BarChart
+	MemChart   *widgets.BarChart
+	CpuHistory *widgets.SparklineGroup
+	MemHistory *widgets.SparklineGroup
+	NameList   *widgets.List
+	InfoList   *widgets.List
+	ImageTable *widgets.Table
+	// replaces the name and info lists in the table view
+	ContainerTable *widgets.Table
+	LogList        *widgets.List
+	EventList      *widgets.List
+	screen         screen
+	status         string
+	filter         string
+	hosts          []string
+	// a message shown blinking in the info bar until flashUntil
+	flash      string
+	flashUntil time.Time
+	columns    []column
+	tableMode  bool
+	// what is being recorded or replayed
+	session string
+	// connection problems per host
+	connErrors map[string]string
+	renderer   renderer
+	// set while something else has the terminal
+	suspended bool
 }
 
 func createBarChart() *widgets.BarChart {
@@ -75,18 +178,28 @@ func createBarChart() *widgets.BarChart {
 	return chart
 }
 
+func createHistory() *widgets.SparklineGroup {
+	line := widgets.NewSparkline()
+	line.LineColor = chartColor
+	group := widgets.NewSparklineGroup(line)
+	group.TitleStyle = titleStyle
+	group.Border = true
+	return group
+}
+
 func createContainerList() *widgets.List {
 	list := widgets.NewList()
 	list.TitleStyle = titleStyle
-	list.TextStyle = ui.Style{Fg: ui.ColorCyan, Bg: ui.ColorClear}
-	list.SelectedRowStyle = ui.Style{Fg: ui.ColorCyan, Bg: ui.ColorClear}
+	list.TextStyle = textStyle
+	list.SelectedRowStyle = textStyle
 	list.Border = true
 	return list
 }
 
-func NewView() *view {
+// NewView creates the widgets, drawn by the given renderer
+func NewView(r renderer) *view {
 
-	var view = view{}
+	var view = view{renderer: r}
 
 	view.InfoBar = widgets.NewParagraph()
 	view.InfoBar.Border = false
@@ -106,76 +219,435 @@ func NewView() *view {
 	view.MemChart = createBarChart()
 	view.MemChart.Title = "%MEM"
 
+	view.CpuHistory = createHistory()
+	view.MemHistory = createHistory()
+
+	view.ImageTable = widgets.NewTable()
+	view.ImageTable.Title = "Images"
+	view.ImageTable.TitleStyle = titleStyle
+	view.ImageTable.TextStyle = textStyle
+	view.ImageTable.RowSeparator = false
+	view.ImageTable.RowStyles[0] = titleStyle
+	view.ImageTable.Rows = [][]string{imageHeaders}
+	view.LogList = widgets.NewList()
+	view.LogList.Title = "Logs"
+	view.LogList.TitleStyle = titleStyle
+	view.LogList.TextStyle = ui.Style{Fg: ui.ColorWhite, Bg: ui.ColorClear}
+	view.LogList.SelectedRowStyle = view.LogList.TextStyle
+
+	view.EventList = widgets.NewList()
+	view.EventList.Title = "Events"
+	view.EventList.TitleStyle = titleStyle
+	view.EventList.TextStyle = textStyle
+	view.EventList.SelectedRowStyle = view.EventList.TextStyle
+
+	view.ImageTable.ColumnResizer = func() {
+		// everything but the repo tag has a fairly fixed width
+		fixed := []int{15, 12, 16, 12}
+		if len(view.ImageTable.Rows) > 0 && len(view.ImageTable.Rows[0]) > len(imageHeaders) {
+			// the host column
+			fixed = append(fixed, 16)
+		}
+		tagWidth := view.ImageTable.Inner.Dx()
+		for _, width := range fixed {
+			tagWidth -= width
+		}
+		view.ImageTable.ColumnWidths = append([]int{tagWidth}, fixed...)
+	}
+
+	view.ContainerTable = widgets.NewTable()
+	view.ContainerTable.TitleStyle = titleStyle
+	view.ContainerTable.TextStyle = textStyle
+	view.ContainerTable.RowSeparator = false
+	view.ContainerTable.ColumnResizer = func() {
+		view.ContainerTable.ColumnWidths = columnWidths(view.columns, view.ContainerTable.Inner.Dx())
+	}
+
 	return &view
 }
 
 func (v *view) SetLayout() {
+	containerRow := ui.NewRow(5.0/12,
+		ui.NewCol(4.0/12, v.NameList),
+		ui.NewCol(8.0/12, v.InfoList),
+	)
+	if v.tableMode {
+		containerRow = ui.NewRow(5.0/12,
+			ui.NewCol(1.0, v.ContainerTable),
+		)
+	}
+
 	v.Grid = ui.NewGrid()
 	v.ResetSize()
 	v.Grid.Set(
 		ui.NewRow(1.0/12,
 			ui.NewCol(1.0, v.InfoBar),
 		),
-		ui.NewRow(3.0/12,
+		ui.NewRow(2.0/12,
 			ui.NewCol(1.0, v.CpuChart),
 		),
-		ui.NewRow(3.0/12,
+		ui.NewRow(2.0/12,
 			ui.NewCol(1.0, v.MemChart),
 		),
-		ui.NewRow(5.0/12,
-			ui.NewCol(4.0/12, v.NameList),
-			ui.NewCol(8.0/12, v.InfoList),
+		ui.NewRow(2.0/12,
+			ui.NewCol(1.0/2, v.CpuHistory),
+			ui.NewCol(1.0/2, v.MemHistory),
+		),
+		containerRow,
+	)
+
+	v.ImageGrid = ui.NewGrid()
+	v.ResetSize()
+	v.ImageGrid.Set(
+		ui.NewRow(1.0/12,
+			ui.NewCol(1.0, v.InfoBar),
+		),
+		ui.NewRow(11.0/12,
+			ui.NewCol(1.0, v.ImageTable),
+		),
+	)
+
+	v.LogGrid = ui.NewGrid()
+	v.ResetSize()
+	v.LogGrid.Set(
+		ui.NewRow(1.0/12,
+			ui.NewCol(1.0, v.InfoBar),
+		),
+		ui.NewRow(11.0/12,
+			ui.NewCol(1.0, v.LogList),
+		),
+	)
+
+	v.EventGrid = ui.NewGrid()
+	v.ResetSize()
+	v.EventGrid.Set(
+		ui.NewRow(1.0/12,
+			ui.NewCol(1.0, v.InfoBar),
+		),
+		ui.NewRow(11.0/12,
+			ui.NewCol(1.0, v.EventList),
 		),
 	)
 }
 
 func (v *view) ResetSize() {
-	termWidth, termHeight := ui.TerminalDimensions()
+	termWidth, termHeight := v.renderer.Size()
 	if termWidth > 20 {
 		v.Grid.SetRect(0, 0, termWidth, termHeight)
+		if v.ImageGrid != nil {
+			v.ImageGrid.SetRect(0, 0, termWidth, termHeight)
+		}
+		if v.LogGrid != nil {
+			v.LogGrid.SetRect(0, 0, termWidth, termHeight)
+		}
+		if v.EventGrid != nil {
+			v.EventGrid.SetRect(0, 0, termWidth, termHeight)
+		}
 	}
 }
 
 func (v *view) Render() {
-	//ui.Clear()
-	ui.Render(v.Grid)
+	if v.suspended {
+		return
+	}
+	switch v.screen {
+	case ImageScreen:
+		v.renderer.Render(v.ImageGrid)
+	case LogScreen:
+		v.renderer.Render(v.LogGrid)
+	case EventScreen:
+		v.renderer.Render(v.EventGrid)
+	default:
+		v.renderer.Render(v.Grid)
+	}
+}
+
+// SetScreen switches between the top level screens
+func (v *view) SetScreen(s screen) {
+	v.screen = s
+	if v.suspended {
+		return
+	}
+	v.renderer.Clear()
+	v.Render()
+}
+
+// Suspend stops drawing while something else has the terminal, like a shell in a container. The widgets are still
+// kept up to date.
+func (v *view) Suspend(suspended bool) {
+	v.suspended = suspended
+}
+
+func (v *view) RenderImages(images imageSlice, containers containerMap, offset int) {
+	v.ImageTable.Rows = append([][]string{images.headers()}, images.rows(offset, containers)...)
+	v.Render()
+}
+
+// RenderLogs shows the lines that fit in the log pane, scrolled back the given number of lines from the end
+func (v *view) RenderLogs(name string, lines []logLine, scrollBack int, paused bool) {
+	var (
+		height = v.LogList.Inner.Dy()
+		end    = len(lines) - scrollBack
+		start  = end - height
+	)
+	if end < 0 {
+		end = 0
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	v.LogList.Rows = make([]string, 0, end-start)
+	for _, line := range lines[start:end] {
+		text := plainText(line.Text)
+		if line.Stderr && len(text) > 0 {
+			v.LogList.Rows = append(v.LogList.Rows, "["+text+"](fg:error)")
+		} else {
+			v.LogList.Rows = append(v.LogList.Rows, text)
+		}
+	}
+
+	v.LogList.Title = "Logs: " + name
+	if paused {
+		v.LogList.Title += " (paused)"
+	}
+	v.Render()
 }
 
-func (v *view) UpdateStats(statsCharts *StatsMsg, offset int) {
+// RenderEvents shows the events of the given type, all of them if it is empty, that fit in the event pane,
+// scrolled back the given number of entries from the newest
+func (v *view) RenderEvents(entries []eventLogEntry, eventType string, scrollBack int) {
+	var (
+		filtered = filterEvents(entries, eventType)
+		height   = v.EventList.Inner.Dy()
+		end      = len(filtered) - scrollBack
+		start    = end - height
+	)
+	if end < 0 {
+		end = 0
+	}
+	if start < 0 {
+		start = 0
+	}
 
-	statsCharts.CpuChart.Offset(offset).UpdateBarChart(v.CpuChart)
-	statsCharts.MemChart.Offset(offset).UpdateBarChart(v.MemChart)
+	v.EventList.Rows = make([]string, 0, end-start)
+	for _, entry := range filtered[start:end] {
+		v.EventList.Rows = append(v.EventList.Rows, entry.line())
+	}
 
+	v.EventList.Title = "Events"
+	if len(eventType) > 0 {
+		v.EventList.Title += ": " + eventType
+	}
+	if scrollBack > 0 {
+		v.EventList.Title += fmt.Sprintf(" (%d newer)", scrollBack)
+	}
 	v.Render()
 }
 
-func (v *view) RenderContainers(containers containerMap, infoType dockerInfoType, listOffset int, inspectMode bool) {
-	names, info := containers.namesAndInfo(listOffset, infoType, inspectMode)
-	v.NameList.Rows = names
-	v.InfoList.Rows = info
-	v.InfoList.Title = infoHeaders[infoType]
+// UpdateHistory sets the recent cpu and memory usage of one container, drawn on the next render
+func (v *view) UpdateHistory(name string, samples []statsSample, window time.Duration) {
+	var (
+		cpu = make([]float64, len(samples))
+		mem = make([]float64, len(samples))
+	)
+	for i, sample := range samples {
+		cpu[i] = sample.CPU
+		mem[i] = sample.Mem
+	}
+	updateHistory(v.CpuHistory, "%CPU", name, cpu, window)
+	updateHistory(v.MemHistory, "%MEM", name, mem, window)
+}
+
+func updateHistory(group *widgets.SparklineGroup, metric string, name string, values []float64, window time.Duration) {
+	var (
+		line = group.Sparklines[0]
+		peak = 0.0
+	)
+	for _, value := range values {
+		if value > peak {
+			peak = value
+		}
+	}
+
+	line.Data = downsample(values, group.Inner.Dx())
+	// keep flat lines at the bottom
+	line.MaxVal = peak
+	if line.MaxVal < 1 {
+		line.MaxVal = 1
+	}
+
+	group.Title = fmt.Sprintf("%s %s %s", metric, windowName(window), name)
+	if len(values) > 0 {
+		group.Title += fmt.Sprintf(" now:%.1f peak:%.1f", values[len(values)-1], peak)
+	}
+}
+
+// RenderContainers draws the lists and charts from the same rows, so bar N is always container N
+func (v *view) RenderContainers(rows listRows, stats map[string]ContainerStats, infoType dockerInfoType, listOffset int, inspectMode bool, order sortOrder) {
+	title := "Name " + order.String()
+	if len(v.filter) > 0 {
+		title += " /" + v.filter
+	}
+	if v.tableMode {
+		v.renderTable(rows, stats, listOffset, title)
+	} else {
+		names, info := rows.namesAndInfo(listOffset, infoType, inspectMode, stats)
+		v.NameList.Rows = names
+		v.NameList.Title = title
+		v.InfoList.Rows = info
+		v.InfoList.Title = infoHeaders[infoType]
+	}
+	v.UpdateStats(rows, stats, listOffset)
+
 	v.Render()
 }
 
+// UpdateStats sets the cpu and memory bars of the running containers from the list offset on,
+// drawn on the next render
+func (v *view) UpdateStats(rows listRows, stats map[string]ContainerStats, listOffset int) {
+	var (
+		cpuChart, memChart = updateStatsBarCharts(rows.containers().runningStats(stats))
+		chartOffset        = rows.runningBefore(listOffset)
+	)
+	cpuChart.Offset(chartOffset).UpdateBarChart(v.CpuChart)
+	memChart.Offset(chartOffset).UpdateBarChart(v.MemChart)
+}
+
+func (v *view) renderTable(rows listRows, stats map[string]ContainerStats, listOffset int, title string) {
+	var (
+		headers                          = make([]string, len(v.columns))
+		cells, stopped, projects, alerts = rows.tableRows(listOffset, v.columns, stats)
+	)
+	for i, col := range v.columns {
+		headers[i] = col.header
+	}
+	v.ContainerTable.Title = title
+	v.ContainerTable.Rows = append([][]string{headers}, cells...)
+	v.ContainerTable.RowStyles = map[int]ui.Style{0: titleStyle}
+	// the header takes the first row of the table
+	for _, i := range stopped {
+		v.ContainerTable.RowStyles[i+1] = ui.Style{Fg: ui.StyleParserColorMap["stopped"], Bg: ui.ColorClear}
+	}
+	for _, i := range projects {
+		v.ContainerTable.RowStyles[i+1] = ui.Style{Fg: ui.StyleParserColorMap["project"], Bg: ui.ColorClear}
+	}
+	for i, level := range alerts {
+		v.ContainerTable.RowStyles[i+1] = alertStyles[level]
+	}
+}
+
+// SetColumns sets the columns of the table view
+func (v *view) SetColumns(columns []column) {
+	v.columns = columns
+}
+
+// SetTableMode switches between the name and info lists and the table, taking effect on the next SetLayout
+func (v *view) SetTableMode(on bool) {
+	v.tableMode = on
+}
+
 func (v view) UpdateInfoBar(currentContainers containerMap, currentStats *StatsMsg) {
 	var (
-		numCons  = len(currentContainers)
+		numCons  = currentContainers.numRunning()
 		totalCpu = 0.0
 		totalMem = 0.0
 	)
 	if currentStats != nil {
-		totalCpu = sum(currentStats.CpuChart.Data...)
-		totalMem = sum(currentStats.MemChart.Data...)
+		for _, cs := range currentStats.Containers {
+			totalCpu += cs.CPUPercent
+			totalMem += cs.MemPercent
+		}
 	}
 
 	v.InfoBar.Text = fmt.Sprintf(" Cons:%d  Total CPU:%d%%  Total Mem:%d%%", numCons, int(totalCpu), int(totalMem))
+	io := currentStats.totals()
+	v.InfoBar.Text += fmt.Sprintf("  Net rx/tx:%s/%s  IO r/w:%s/%s",
+		rateString(io.NetRxRate), rateString(io.NetTxRate), rateString(io.BlockReadRate), rateString(io.BlockWriteRate))
+	if numStopped := len(currentContainers) - numCons; numStopped > 0 {
+		v.InfoBar.Text += fmt.Sprintf("  Stopped:%d", numStopped)
+	}
+	if len(v.session) > 0 {
+		v.InfoBar.Text += "  [" + plainText(v.session) + "](fg:warning)"
+	}
+	if len(v.status) > 0 {
+		v.InfoBar.Text += "  " + plainText(v.status)
+	}
+	if time.Now().Before(v.flashUntil) {
+		style := "fg:error"
+		if time.Now().Unix()%2 == 0 {
+			style += ",mod:reverse"
+		}
+		v.InfoBar.Text += "  [" + plainText(v.flash) + "](" + style + ")"
+	}
+	for _, host := range sortedKeys(v.connErrors) {
+		v.InfoBar.Text += "  [" + plainText(v.connErrors[host]) + "](fg:error)"
+	}
+	if len(v.hosts) > 0 {
+		v.InfoBar.Text += "\n" + hostTotals(v.hosts, currentContainers, currentStats)
+	}
 	v.Render()
 }
 
-func sum(nums ...float64) float64 {
-	total := 0.0
-	for _, num := range nums {
-		total += num
+// hostTotals sums up the running containers and their usage per host
+func hostTotals(hosts []string, currentContainers containerMap, currentStats *StatsMsg) string {
+	var (
+		numCons  = make(map[string]int)
+		totalCpu = make(map[string]float64)
+		totalMem = make(map[string]float64)
+		text     = ""
+	)
+	for _, cont := range currentContainers {
+		if cont.running() {
+			numCons[cont.host]++
+		}
 	}
-	return total
+	if currentStats != nil {
+		for _, cs := range currentStats.Containers {
+			totalCpu[cs.Host] += cs.CPUPercent
+			totalMem[cs.Host] += cs.MemPercent
+		}
+	}
+	for _, host := range hosts {
+		text += fmt.Sprintf(" %s Cons:%d CPU:%d%% Mem:%d%% ", host, numCons[host], int(totalCpu[host]), int(totalMem[host]))
+	}
+	return text
+}
+
+// SetHosts names the docker hosts to show totals for, there are none to show for a single host
+func (v *view) SetHosts(hosts []string) {
+	v.hosts = hosts
+}
+
+// SetConnStatus shows or, once reconnected, clears a host's connection problem
+func (v *view) SetConnStatus(status connStatus) {
+	if v.connErrors == nil {
+		v.connErrors = make(map[string]string)
+	}
+	if status.Err == nil {
+		delete(v.connErrors, status.Host)
+		return
+	}
+	v.connErrors[status.Host] = status.String()
+}
+
+// SetFilter sets the search shown in the container list title
+func (v *view) SetFilter(filter string) {
+	v.filter = filter
+}
+
+// Flash shows a message in the info bar for a while, blinking on every refresh
+func (v *view) Flash(msg string) {
+	v.flash = msg
+	v.flashUntil = time.Now().Add(flashDuration)
+}
+
+// SetSession shows what is being recorded or replayed in the info bar
+func (v *view) SetSession(session string) {
+	v.session = session
+}
+
+// SetStatus sets a message to show in the info bar, next to the totals
+func (v *view) SetStatus(msg string) {
+	v.status = msg
 }
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	goDocker "github.com/fsouza/go-dockerclient"
+	ui "github.com/gizak/termui/v3"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares the screen with testdata/<name>.golden, rewriting the file instead with -update
+func golden(t *testing.T, name string, screen *bufferRenderer) {
+	t.Helper()
+	var (
+		path = filepath.Join("testdata", name+".golden")
+		got  = screen.String()
+	)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	if got != string(want) {
+		t.Errorf("screen differs from %s, run the tests with -update to accept it\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// testContainer is started, or finished if it isn't running, the given time ago, so the uptimes render the same on
+// every run
+func testContainer(id string, name string, image string, running bool, ago time.Duration, labels map[string]string) container {
+	cont := &goDocker.Container{
+		ID:              fakeID(id),
+		Name:            "/" + name,
+		Config:          &goDocker.Config{Image: image, Labels: labels},
+		HostConfig:      &goDocker.HostConfig{},
+		NetworkSettings: &goDocker.NetworkSettings{},
+	}
+	cont.State.Running = running
+	if running {
+		cont.State.StartedAt = time.Now().Add(-ago)
+	} else {
+		cont.State.StartedAt = time.Now().Add(-2 * ago)
+		cont.State.FinishedAt = time.Now().Add(-ago)
+		cont.State.ExitCode = 1
+	}
+	return container{Container: cont}
+}
+
+func testContainers() (containerMap, StatsMsg) {
+	var (
+		shop = func(service string) map[string]string {
+			return map[string]string{composeProjectLabel: "shop", composeServiceLabel: service}
+		}
+		containers = containerMap{}
+		stats      = StatsMsg{}
+	)
+	for _, cont := range []container{
+		testContainer("a", "shop-web-1", "nginx:1.25", true, 3*time.Hour, shop("web")),
+		testContainer("b", "shop-db-1", "postgres:16", true, 5*time.Hour, shop("db")),
+		testContainer("c", "worker", "busybox", true, 2*time.Hour, nil),
+		testContainer("d", "migrate", "busybox", false, 4*time.Hour, nil),
+	} {
+		containers[cont.ID] = cont
+	}
+	for _, cs := range []ContainerStats{
+		{ID: fakeID("a"), Name: "shop-web-1", CPUPercent: 12, MemPercent: 20, MemUsage: 200 << 20, MemLimit: 1 << 30},
+		{ID: fakeID("b"), Name: "shop-db-1", CPUPercent: 35, MemPercent: 85, MemUsage: 870 << 20, MemLimit: 1 << 30},
+		{ID: fakeID("c"), Name: "worker", CPUPercent: 97, MemPercent: 5, MemUsage: 50 << 20, MemLimit: 1 << 30,
+			ioRates: ioRates{NetRxRate: 2048, NetTxRate: 1024}},
+	} {
+		cs.Samples = 2
+		stats.Containers = append(stats.Containers, cs)
+	}
+	return containers, stats
+}
+
+func TestScreenshotGolden(t *testing.T) {
+	columns, err := parseColumns("name,image,status,cpu,mem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers, stats := testContainers()
+
+	for _, test := range []struct {
+		name string
+		opts screenshotOptions
+	}{
+		{"list", screenshotOptions{infoType: StatusInfo}},
+		{"list_all", screenshotOptions{showAll: true, infoType: ImageInfo}},
+		{"grouped", screenshotOptions{showAll: true, grouped: true, infoType: ImageInfo}},
+		{"table", screenshotOptions{tableMode: true, columns: columns}},
+		{"narrow", screenshotOptions{tableMode: true, columns: columns, width: 60, height: 36}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.opts
+			opts.order = sortOrder{key: SortName}
+			if opts.width == 0 {
+				opts.width, opts.height = 100, 40
+			}
+			golden(t, "screenshot_"+test.name, screenshot(listenerSet{&StatsListener{}}, containers, stats, opts))
+		})
+	}
+}
+
+func TestRenderContainersScrolled(t *testing.T) {
+	var (
+		screen            = newBufferRenderer(80, 36)
+		v                 = NewView(screen)
+		containers, stats = testContainers()
+		byID              = stats.byID()
+		order             = sortOrder{key: SortName}
+		rows              = flatRows(containers.visible(true).sorted(order, byID))
+	)
+	v.SetLayout()
+	v.UpdateInfoBar(containers, &stats)
+	// the first row is scrolled off, the charts follow the list
+	v.RenderContainers(rows, byID, ImageInfo, 1, false, order)
+	golden(t, "containers_scrolled", screen)
+
+	v.UpdateStats(rows, byID, 2)
+	v.Render()
+	golden(t, "stats_scrolled", screen)
+}
+
+func TestUpdateInfoBarShowsHosts(t *testing.T) {
+	var (
+		screen            = newBufferRenderer(160, 60)
+		v                 = NewView(screen)
+		containers, stats = testContainers()
+	)
+	for id, cont := range containers {
+		cont.host = "alpha"
+		if cont.shortName() == "worker" {
+			cont.host = "beta"
+		}
+		containers[id] = cont
+	}
+	for i, cs := range stats.Containers {
+		stats.Containers[i].Host = containers[cs.ID].host
+	}
+	v.SetLayout()
+	v.SetHosts([]string{"alpha", "beta"})
+	v.SetConnStatus(connStatus{Host: "beta", Err: errEventsClosed})
+	v.SetStatus("Stop worker...")
+	v.UpdateInfoBar(containers, &stats)
+	golden(t, "infobar_hosts", screen)
+}
+
+func TestUpdateInfoBarKeepsBracketsAsText(t *testing.T) {
+	var (
+		screen            = newBufferRenderer(160, 60)
+		v                 = NewView(screen)
+		containers, stats = testContainers()
+	)
+	v.SetLayout()
+	v.SetSession("[rec] incident.dockdash")
+	v.SetStatus("Stop shop-web-1 failed: [409] container is paused")
+	v.Flash("[shop-db-1] is unhealthy")
+	v.SetConnStatus(connStatus{Host: "v6", Err: errors.New(`dial tcp [::1]:2376: connect: connection refused`), Retry: time.Second})
+	v.UpdateInfoBar(containers, &stats)
+	golden(t, "infobar_brackets", screen)
+}
+
+func TestTableRowsAreStyledByAlertLevel(t *testing.T) {
+	var (
+		screen            = newBufferRenderer(100, 40)
+		containers, stats = testContainers()
+		columns, _        = parseColumns("name,cpu")
+	)
+	v := NewView(screen)
+	v.SetColumns(columns)
+	v.SetTableMode(true)
+	v.SetLayout()
+	byID := stats.byID()
+	order := sortOrder{key: SortName}
+	v.RenderContainers(flatRows(containers.visible(false).sorted(order, byID)), byID, ImageInfo, 0, false, order)
+
+	// under the header row: shop-db-1, shop-web-1 then worker
+	var (
+		top    = v.ContainerTable.Inner.Min.Y + 1
+		x      = v.ContainerTable.Inner.Min.X + 1
+		db     = screen.Cell(x, top)
+		web    = screen.Cell(x, top+1)
+		worker = screen.Cell(x, top+2)
+	)
+	if db.Style != alertStyles[WarningLevel] {
+		t.Errorf("shop-db-1 is over the memory warning threshold, got style %+v", db.Style)
+	}
+	if web.Style != alertStyles[NormalLevel] && web.Style != textStyle {
+		t.Errorf("shop-web-1 is under the thresholds, got style %+v", web.Style)
+	}
+	if worker.Style != alertStyles[CriticalLevel] {
+		t.Errorf("worker is over the cpu critical threshold, got style %+v", worker.Style)
+	}
+}
+
+func TestBufferRendererClips(t *testing.T) {
+	var (
+		screen = newBufferRenderer(10, 3)
+		v      = NewView(screen)
+	)
+	v.InfoBar.Text = "far too long for the screen"
+	v.InfoBar.Title = ""
+	// the paragraph is drawn inside its rect, which starts off screen
+	v.InfoBar.SetRect(-2, 0, 40, 3)
+	screen.Render(v.InfoBar)
+	if got, want := screen.String(), "\nar too lon\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	screen.Clear()
+	if got := screen.String(); got != "\n\n\n" {
+		t.Errorf("expected a blank screen after clearing, got %q", got)
+	}
+}
+
+func TestRenderLogsKeepsMarkupAsText(t *testing.T) {
+	var (
+		screen = newBufferRenderer(80, 20)
+		v      = NewView(screen)
+	)
+	v.SetLayout()
+	v.SetScreen(LogScreen)
+	v.RenderLogs("web", []logLine{
+		{Text: "[INFO](fg:red) ready"},
+		{Text: "level=error msg=[failed", Stderr: true},
+	}, 0, false)
+
+	got := screen.String()
+	for _, want := range []string{"(INFO)(fg:red) ready", "level=error msg=(failed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q on the screen, got:\n%s", want, got)
+		}
+	}
+	var (
+		x      = v.LogList.Inner.Min.X
+		y      = v.LogList.Inner.Min.Y
+		stdout = screen.Cell(x, y)
+		stderr = screen.Cell(x, y+1)
+	)
+	if stdout.Style.Fg == ui.StyleParserColorMap["red"] {
+		t.Error("expected the markup in the log line not to style it")
+	}
+	if stderr.Style.Fg != ui.StyleParserColorMap["error"] {
+		t.Errorf("expected stderr in the error color, got %+v", stderr.Style)
+	}
+}
+
+func TestRenderContainersWithoutConfig(t *testing.T) {
+	var (
+		screen = newBufferRenderer(160, 60)
+		v      = NewView(screen)
+		cont   = testContainer("a", "web", "nginx", true, time.Hour, nil)
+		order  = sortOrder{key: SortName}
+	)
+	// none of these are guaranteed to be set
+	cont.Config, cont.HostConfig, cont.NetworkSettings = nil, nil, nil
+	v.SetLayout()
+	for infoType := ImageInfo; infoType <= BlockIOInfo; infoType++ {
+		for _, inspectMode := range []bool{false, true} {
+			v.RenderContainers(flatRows(containerSlice{cont}), nil, infoType, 0, inspectMode, order)
+		}
+	}
+	if !strings.Contains(screen.String(), "web") {
+		t.Errorf("expected web in the list, got:\n%s", screen.String())
+	}
+}